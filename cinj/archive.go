@@ -0,0 +1,126 @@
+package cinj
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// archiveSeparator separates the path of an archive from the path of a
+// member inside of it, as in ./samples.zip!/client/main.py
+const archiveSeparator = "!/"
+
+// archiveExtensions are the archive formats members can be read from
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// maxListedMembers is the number of members named when a member is not found
+const maxListedMembers = 10
+
+// splitArchive splits a path such as ./samples.zip!/client/main.py into the
+// path of the archive and the path of the member
+func splitArchive(p string) (string, string, bool) {
+	archive, member, found := strings.Cut(filepath.ToSlash(p), archiveSeparator)
+	if !found || archiveKind(archive) == "" {
+		return p, "", false
+	}
+	return filepath.FromSlash(archive), member, true
+}
+
+// archiveKind returns the extension of an archive path from
+// archiveExtensions, or an empty string for other files
+func archiveKind(p string) string {
+	lower := strings.ToLower(p)
+	kind := ""
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) && len(ext) > len(kind) {
+			kind = ext
+		}
+	}
+	return kind
+}
+
+// readMember returns the content of a member of a zip, tar or gzipped tar
+// archive
+func readMember(archive string, content []byte, member string) ([]byte, error) {
+	want := cleanMember(member)
+	if want == "" {
+		return nil, fmt.Errorf("No member given for archive %s", filepath.Base(archive))
+	}
+
+	names := []string{}
+	switch archiveKind(archive) {
+	case ".zip":
+		zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, fmt.Errorf("Could not read archive %s: %w", filepath.Base(archive), err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if cleanMember(f.Name) == want {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+			names = append(names, cleanMember(f.Name))
+		}
+	default:
+		var r io.Reader = bytes.NewReader(content)
+		if kind := archiveKind(archive); kind == ".tar.gz" || kind == ".tgz" {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read archive %s: %w", filepath.Base(archive), err)
+			}
+			defer gz.Close()
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Could not read archive %s: %w", filepath.Base(archive), err)
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if cleanMember(hdr.Name) == want {
+				return io.ReadAll(tr)
+			}
+			names = append(names, cleanMember(hdr.Name))
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("Could not find %s, archive %s has no files",
+			want, filepath.Base(archive))
+	}
+	sort.Strings(names)
+	listed := names
+	if len(listed) > maxListedMembers {
+		listed = append(listed[:maxListedMembers:maxListedMembers], "...")
+	}
+	return nil, fmt.Errorf("Could not find %s in archive %s, it has %s",
+		want, filepath.Base(archive), strings.Join(listed, ", "))
+}
+
+// cleanMember normalizes the path of an archive member, which can start with
+// ./ or / in tar archives
+func cleanMember(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))
+	return strings.TrimPrefix(name, "/")
+}
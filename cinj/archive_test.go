@@ -0,0 +1,67 @@
+package cinj
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveMembers(t *testing.T) {
+	dir := t.TempDir()
+	main := "def run():\n    return 1\n"
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for name, content := range map[string]string{"client/main.py": main, "README.txt": "samples\n"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	var tarred bytes.Buffer
+	gz := gzip.NewWriter(&tarred)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "./client/main.py", Mode: 0o644, Size: int64(len(main)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(main))
+	tw.Close()
+	gz.Close()
+
+	files := map[string][]byte{
+		"samples.zip":    zipped.Bytes(),
+		"samples.tar.gz": tarred.Bytes(),
+		"report.cinj": []byte("cinj{./samples.zip!/client/main.py --function=run --caption}\n\n" +
+			"cinj{./samples.tar.gz!/client/main.py --function=run}\n\n"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	c := Cinj{Filepath: filepath.Join(dir, "report.cinj"), Newname: filepath.Join(dir, "report.md")}
+	if err := c.Run(); err != nil {
+		t.Fatal(err.Error())
+	}
+	got, err := os.ReadFile(c.Newname)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	block := "```python\ndef run():\n    return 1\n```\n"
+	expected := "Listing 1: `run` — samples.zip!/client/main.py, lines 1–2\n\n" + block + block
+	if string(got) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "report.cinj"), []byte("cinj{./samples.zip!/main.py}\n"), 0o644)
+	if err := c.Run(); err == nil || !strings.Contains(err.Error(), "it has README.txt, client/main.py") {
+		t.Fatalf("expected a missing member error listing the members, got %v", err)
+	}
+}
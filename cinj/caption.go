@@ -0,0 +1,151 @@
+package cinj
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Caption positions, relative to the code block
+const (
+	captionAbove = "above"
+	captionBelow = "below"
+)
+
+// defaultCaptionFormat is used when neither the config nor the cinj command
+// set a caption format
+const defaultCaptionFormat = "Listing {{.Number}}: {{if .Name}}{{code .Name}} — {{end}}" +
+	"{{.Path}}{{if .Commit}} at {{.Commit}}{{end}}{{if .Lines}}, lines {{.Lines}}{{end}}"
+
+// CaptionConfig sets up the captions written with each snippet
+type CaptionConfig struct {
+	// Enabled captions every snippet, not only the commands with --caption
+	Enabled bool `json:"enabled"`
+	// Format is a text/template format string, see Caption for the fields
+	// it can use
+	Format string `json:"format"`
+	// Position is above or below, defaulting to above
+	Position string `json:"position"`
+}
+
+// check returns an error when the format or the position are not valid
+func (cc CaptionConfig) check() error {
+	if cc.Format != "" {
+		if _, err := parseCaptionFormat(cc.Format); err != nil {
+			return err
+		}
+	}
+	return checkCaptionPosition(cc.Position)
+}
+
+// Caption holds the fields a caption format can use
+type Caption struct {
+	Number    int    // listing number, counting the captioned snippets
+	Name      string // what was extracted, such as Client.fetch
+	Path      string // source file, relative to the file being worked on
+	Revision  string // git revision the source was read from, such as v1.4
+	Commit    string // short hash of the commit of the revision
+	Lines     string // source lines, such as 40–72
+	StartLine int
+	EndLine   int
+	Language  string
+}
+
+// parseCaptionFormat parses a caption format. Besides the fields of Caption,
+// the format can use the code function, which writes its argument as inline
+// code in the markup of the output format
+func parseCaptionFormat(format string) (*template.Template, error) {
+	return parseCaptionFormatFor(format, outputFormats[FormatMarkdown])
+}
+
+func parseCaptionFormatFor(format string, output outputFormat) (*template.Template, error) {
+	tmpl, err := template.New("caption").
+		Funcs(template.FuncMap{"code": output.code}).
+		Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid caption format: %w", err)
+	}
+	return tmpl, nil
+}
+
+func checkCaptionPosition(position string) error {
+	switch position {
+	case "", captionAbove, captionBelow:
+		return nil
+	}
+	return fmt.Errorf("Unknown caption position %s, expected above or below",
+		position)
+}
+
+// caption returns the caption for a snippet of a cinj command, and whether
+// the snippet should have one at all
+func (c *Cinj) caption(cmd CinjCommand, snippet Snippet, render renderArgs) (string, bool, error) {
+	enabled := c.Config.Captions.Enabled || render.caption || render.captionFormat != ""
+	if !enabled || render.noCaption {
+		return "", false, nil
+	}
+
+	output := c.format()
+	format := render.captionFormat
+	if format == "" {
+		format = c.Config.Captions.Format
+	}
+	if format == "" {
+		format = output.captionFormat
+	}
+	tmpl, err := parseCaptionFormatFor(format, output)
+	if err != nil {
+		return "", false, err
+	}
+
+	paths := cmd.Filepaths
+	if len(paths) == 0 {
+		paths = []string{cmd.Filepath}
+	}
+	shown := []string{}
+	for _, p := range paths {
+		rel, err := filepath.Rel(filepath.Dir(c.Filepath), p)
+		if err == nil && filepath.IsLocal(rel) {
+			p = rel
+		}
+		shown = append(shown, filepath.ToSlash(p))
+	}
+	// the files of a diff are shown as old → new
+	path := strings.Join(shown, " → ")
+
+	escape := output.escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+
+	c.listings++
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, Caption{
+		Number:    c.listings,
+		Name:      escape(snippet.Name),
+		Path:      escape(path),
+		Revision:  escape(cmd.revision(0)),
+		Commit:    snippet.Commit,
+		Lines:     snippet.lineRange(),
+		StartLine: snippet.StartLine,
+		EndLine:   snippet.EndLine,
+		Language:  escape(snippet.Language.String()),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("Could not write caption: %w", err)
+	}
+
+	return sb.String(), true, nil
+}
+
+// captionPosition returns where the caption of a cinj command goes
+func (c *Cinj) captionPosition(render renderArgs) string {
+	if render.captionPosition != "" {
+		return render.captionPosition
+	}
+	if c.Config.Captions.Position != "" {
+		return c.Config.Captions.Position
+	}
+	return captionAbove
+}
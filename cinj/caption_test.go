@@ -0,0 +1,43 @@
+package cinj
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCaptions(t *testing.T) {
+	dir := t.TempDir()
+	source := "import os\n\n\nclass Client:\n    def fetch(self):\n        return 1\n"
+	err := os.WriteFile(filepath.Join(dir, "client.py"), []byte(source), 0o644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	src := filepath.Join(dir, "report.cinj")
+	err = os.WriteFile(src, []byte("cinj{./client.py --class=Client --function=fetch --context=none}\n\n"+
+		"cinj{./client.py --imports --no-caption}\n\n"+
+		"cinj{./client.py --class=Client --caption-position=below --caption-format=\"{{.Number}}. {{.Name}}\"}\n\n"),
+		0o644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	c := Cinj{Filepath: src, Newname: filepath.Join(dir, "report.md")}
+	c.Config.Captions.Enabled = true
+	if err := c.Run(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	got, err := os.ReadFile(c.Newname)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "Listing 1: `Client.fetch` — client.py, lines 5–6\n\n" +
+		"```python\n    def fetch(self):\n        return 1\n```\n" +
+		"```python\nimport os\n```\n" +
+		"```python\nclass Client:\n    def fetch(self):\n        return 1\n```\n\n2. Client\n\n"
+	if string(got) != expected {
+		t.Fatalf("Expected \n%s\nGot \n%s", expected, got)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,6 +15,15 @@ type Cinj struct {
 	Newname  string
 	SrcFile  *os.File
 	DestFile *os.File
+	Config   Config
+	// Format is the output format of the new file, Markdown when empty
+	Format string
+	// CheckSecrets fails the run when a snippet still has a match of a
+	// redaction rule or a built-in detector once it is redacted
+	CheckSecrets bool
+
+	listings int             // number of captioned snippets written so far
+	dest     io.StringWriter // where the new text is written to
 }
 
 // Run executes the Cinj command, creating the new file as long as there
@@ -52,42 +61,97 @@ func (c *Cinj) Run() error {
 // cinj writes the new content from the cinj commands within the initial file
 // into a new file
 func (c *Cinj) cinj() error {
+	if err := checkFlavor(c.Config.Flavor); err != nil {
+		return err
+	}
+	if err := checkFormat(c.Format); err != nil {
+		return err
+	}
+	redactors, err := c.Config.Redaction.detectors(false)
+	if err != nil {
+		return err
+	}
+	checkers, err := c.Config.Redaction.detectors(true)
+	if err != nil {
+		return err
+	}
+
+	// formats that turn the whole text into a document, such as HTML, are
+	// written to a buffer first
+	var document strings.Builder
+	c.dest = c.DestFile
+	if c.format().document != nil {
+		c.dest = &document
+	}
+
 	srcScanner := bufio.NewScanner(c.SrcFile)
+	lineNum := 0
+	c.listings = 0
 
 	for srcScanner.Scan() {
 		line := srcScanner.Text()
+		lineNum++
 
 		if strings.HasPrefix(line, "cinj") {
 			command, err := c.getCinjCommand(line)
 			if err != nil {
-				log.Fatal(err)
-				return err
+				return &DirectiveError{Line: lineNum, Directive: line, Err: err}
 			}
 
-			language := command.fileExtForMarkDown()
-			content, err := c.getContentFromCommand(command)
+			render, err := parseRenderArgs(command.SuppArgs)
 			if err != nil {
-				return err
+				return &DirectiveError{Line: lineNum, Directive: line, Err: err}
+			}
+			if c.Config.Flavor != "" {
+				render.flavor = c.Config.Flavor
 			}
+			render.gutter = c.format().lineGutter
+
+			render.captionPosition = c.captionPosition(*render)
+
+			// a glob writes a snippet for every file it matches
+			commands := command.each()
+			for i, one := range commands {
+				if i > 0 {
+					c.dest.WriteString("\n")
+				}
+				if render.eachHeading != "" {
+					heading, err := c.heading(render.eachHeading, one, i+1, len(commands))
+					if err != nil {
+						return &DirectiveError{Line: lineNum, Directive: line, Err: err}
+					}
+					c.dest.WriteString(heading + "\n\n")
+				}
 
-			contentScanner := bufio.NewScanner(
-				strings.NewReader(content))
-			c.DestFile.WriteString("```" + language.String() + "\n")
-			for contentScanner.Scan() {
-				contentLine := contentScanner.Text()
-				_, err := c.DestFile.WriteString(contentLine + "\n")
+				snippet, err := c.getSnippet(one, *render)
 				if err != nil {
-					fmt.Println(err)
-					return err
+					return &DirectiveError{Line: lineNum, Directive: line, Err: err}
+				}
+				snippet = redact(snippet, redactors)
+				if c.CheckSecrets {
+					if err := checkSecrets(snippet, checkers); err != nil {
+						return &DirectiveError{Line: lineNum, Directive: line, Err: err}
+					}
+				}
+
+				err = c.writeSnippet(one, snippet, *render)
+				if err != nil {
+					return &DirectiveError{Line: lineNum, Directive: line, Err: err}
 				}
 			}
-			c.DestFile.WriteString("```\n")
 			srcScanner.Scan()
+			lineNum++
 
 		} else {
-			c.DestFile.WriteString(line + "\n")
+			c.dest.WriteString(line + "\n")
 		}
 	}
+
+	if c.format().document != nil {
+		name := strings.TrimSuffix(filepath.Base(c.Newname), filepath.Ext(c.Newname))
+		_, err := c.DestFile.WriteString(c.format().document(document.String(), name))
+		return err
+	}
 	return nil
 }
 
@@ -100,33 +164,171 @@ func (c Cinj) getCinjCommand(s string) (CinjCommand, error) {
 		return cmd, errors.New("Cinj command found too short, must contain 'cinj{arg}' at minimum")
 	}
 
-	content := s[5 : len(s)-1]
-	contentSplit := strings.Split(content, " ")
+	content := strings.TrimSuffix(strings.TrimSpace(s[5:]), "}")
+	contentSplit, err := splitArgs(content)
+	if err != nil {
+		return cmd, err
+	}
+	if len(contentSplit) == 0 {
+		return cmd, errors.New("Cinj command found without a file path")
+	}
 
-	cmd.Filepath = contentSplit[0]
-	if filepath.IsLocal(cmd.Filepath) {
-		resolvedPath := filepath.Join(filepath.Dir(c.Filepath), cmd.Filepath)
-		cmd.Filepath = resolvedPath
-		cmd.FileType = cmd.fileExtForMarkDown()
+	paths := 1
+	switch contentSplit[0] {
+	case VerbDiff:
+		cmd.Verb = VerbDiff
+		paths = 2
+		contentSplit = contentSplit[1:]
+		if len(contentSplit) < paths || strings.HasPrefix(contentSplit[1], "-") {
+			return cmd, errors.New("The diff command takes two file paths, as in cinj{diff ./old.py ./new.py}")
+		}
+	case VerbTree:
+		cmd.Verb = VerbTree
+		contentSplit = contentSplit[1:]
+		if len(contentSplit) < paths || strings.HasPrefix(contentSplit[0], "-") {
+			return cmd, errors.New("The tree command takes a directory, as in cinj{tree ./src}")
+		}
 	}
-	if len(contentSplit) > 1 {
-		cmd.Args = contentSplit[1:]
+
+	sArgs := newSourceArgs()
+	sourceFlags := newSourceFlagSet(sArgs)
+	own, rest := splitFlags(sourceFlags, contentSplit[paths:])
+	if err := sourceFlags.Parse(own); err != nil {
+		return cmd, err
+	}
+
+	if sArgs.sha256 != "" {
+		if err := checkSumFormat(sArgs.sha256); err != nil {
+			return cmd, err
+		}
+	}
+	cmd.SHA256 = sArgs.sha256
+
+	for _, path := range contentSplit[:paths] {
+		if !isURL(path) && filepath.IsLocal(path) {
+			path = filepath.Join(filepath.Dir(c.Filepath), path)
+		}
+		path, rev := splitRevision(path)
+		if sArgs.rev != "" {
+			rev = sArgs.rev
+		}
+
+		matches := []string{path}
+		if cmd.Verb == "" && isGlob(path) {
+			cmd.Glob = contentSplit[0]
+			matches, err = expandGlob(path)
+			if err != nil {
+				return cmd, err
+			}
+			if len(matches) == 0 {
+				return cmd, fmt.Errorf("No files match %s", cmd.Glob)
+			}
+		}
+		for _, match := range matches {
+			cmd.Filepaths = append(cmd.Filepaths, match)
+			cmd.Revisions = append(cmd.Revisions, rev)
+		}
+	}
+	cmd.Filepath = cmd.Filepaths[0]
+	cmd.FileType = c.extractorFor(cmd.Filepath).FileType()
+	switch cmd.Verb {
+	case VerbDiff:
+		cmd.FileType = Diff
+	case VerbTree:
+		cmd.FileType = Plain
+	}
+	if len(rest) > 0 {
+		cmd.SuppArgs, cmd.Args = splitRenderArgs(rest)
 	}
 
 	return cmd, nil
 }
 
-// getContentFromCommand calls the appropriate file parsing method for
-// each CinjCommand found in a file.
+// getContentFromCommand reads the file of a CinjCommand and calls the
+// extractor registered for its file type.
 //
 // The function returns any error found in the file parsing method.
-func (c Cinj) getContentFromCommand(cmd CinjCommand) (string, error) {
-	switch cmd.FileType {
-	case Python:
-		content, err := cmd.python()
-		return content, err
+func (c Cinj) getContentFromCommand(cmd CinjCommand) (Snippet, error) {
+	return c.extract(cmd.Filepath, cmd.revision(0), cmd.SHA256, cmd.Args)
+}
+
+// getSnippet returns the snippet of a CinjCommand with the transforms of its
+// rendering arguments applied, such as --dedent
+func (c Cinj) getSnippet(cmd CinjCommand, render renderArgs) (Snippet, error) {
+	var snippet Snippet
+	var err error
+	switch cmd.Verb {
+	case VerbDiff:
+		// the transforms are applied to both files before they are compared
+		return c.diffSnippets(cmd, render)
+	case VerbTree:
+		snippet, err = c.treeSnippet(cmd)
 	default:
-		content, err := cmd.returnAll()
-		return content, err
+		snippet, err = c.getContentFromCommand(cmd)
+	}
+	if err != nil {
+		return snippet, err
+	}
+	return render.transform(snippet)
+}
+
+// extract reads a file, from the git revision `rev` when it is set, checks
+// its SHA-256 against `sum` when it is set, and calls the extractor
+// registered for its file type
+func (c Cinj) extract(path string, rev string, sum string, args []string) (Snippet, error) {
+	extractor := c.extractorFor(path)
+	if err := checkFlags(extractor, args); err != nil {
+		return Snippet{}, err
+	}
+
+	src, commit, err := c.readSource(path, rev, sum)
+	if err != nil {
+		return Snippet{}, err
 	}
+
+	snippet, err := extractor.Extract(src, args)
+	snippet.Commit = commit
+	return snippet, err
+}
+
+// writeSnippet writes a snippet into the new file as a code block of the
+// output format, with its caption when it has one. The parts of a snippet are
+// written as separate blocks, the caption going with the first part, or the
+// last part when it is placed below
+func (c *Cinj) writeSnippet(cmd CinjCommand, snippet Snippet, render renderArgs) error {
+	caption, _, err := c.caption(cmd, snippet, render)
+	if err != nil {
+		return err
+	}
+
+	parts := snippet.Parts
+	if len(parts) == 0 {
+		parts = []Snippet{snippet}
+	}
+
+	output := c.format()
+	for i, part := range parts {
+		partCaption := ""
+		if (render.captionPosition == captionBelow && i == len(parts)-1) ||
+			(render.captionPosition != captionBelow && i == 0) {
+			partCaption = caption
+		}
+
+		block, err := render.codeBlock(part, partCaption)
+		if err != nil {
+			return err
+		}
+		text, err := output.writeBlock(block)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			text = "\n" + text
+		}
+		if _, err := c.dest.WriteString(text); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,48 +1,90 @@
 package cinj
 
 import (
-	"log"
-	"os"
-	"path/filepath"
+	"fmt"
+	"strings"
 )
 
 type CinjCommand struct {
+	// Verb is the verb the command starts with, such as diff, or empty for
+	// a command including a single file
+	Verb     string
 	Filepath string
-	Args     []string
+	// Glob is the pattern of a command including every file it matches, such
+	// as ./handlers/*.py, with the matches in Filepaths
+	Glob string
+	// Filepaths holds every file of a verb that takes more than one, such
+	// as the old and new files of a diff. Filepath is the first of them
+	Filepaths []string
+	// Revisions holds the git revision every file is read from, empty for
+	// files read from the working tree
+	Revisions []string
+	// SHA256 is the hex encoded SHA-256 every file must have, empty when
+	// the files are not checked
+	SHA256   string
+	Args     []string // arguments passed to the extractor
 	FileType Filetype
-	SuppArgs []string
+	SuppArgs []string // arguments that change how the snippet is written
 }
 
-func (cmd CinjCommand) extractContent() ([]string, error) {
-	return []string{}, nil
-}
-
-// returnAll simply returns all of the content inside of a file.
-// This is called when a cinj command does not have any other arguments.
-func (cmd CinjCommand) returnAll() (string, error) {
-	content, err := os.ReadFile(cmd.Filepath)
-	if err != nil {
-		log.Fatal(err)
-		return "", err
+// revision returns the git revision the file at index i of the command is
+// read from
+func (cmd CinjCommand) revision(i int) string {
+	if i < len(cmd.Revisions) {
+		return cmd.Revisions[i]
 	}
+	return ""
+}
 
-	return string(content), nil
+// splitArgs splits the content of a cinj command on spaces, keeping text
+// inside of double or single quotes together so that argument values can
+// contain spaces, for example --separator="# ..."
+// The quotes are removed, and inside of double quotes a backslash escapes
+// a double quote or a backslash
+func splitArgs(s string) ([]string, error) {
+	args := []string{}
+	var sb strings.Builder
+	var quote rune
+	inArg := false
+	escaped := false
 
-}
+	for _, r := range s {
+		switch {
+		case escaped:
+			// as in a shell, only a quote or a backslash is escaped, so the
+			// backslashes of a regular expression such as \( are kept
+			if r != '"' && r != '\\' {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			sb.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteRune(r)
+			inArg = true
+		}
+	}
 
-// fileExtForMarkDown returns a Filetype depending on the extension
-// of the file found in the cinj command.
-func (cmd CinjCommand) fileExtForMarkDown() Filetype {
-	switch filepath.Ext(cmd.Filepath) {
-	case ".py":
-		return Python
-	case ".js":
-		return Javascript
-	case ".txt":
-		return Text
-	case ".md":
-		return Markdown
+	if quote != 0 {
+		return args, fmt.Errorf("Unterminated %c quote in cinj command", quote)
+	}
+	if inArg {
+		args = append(args, sb.String())
 	}
 
-	return Plain
+	return args, nil
 }
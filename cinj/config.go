@@ -0,0 +1,89 @@
+package cinj
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConfigName is the name of the config file Cinj looks for next to the file
+// being worked on when no config file is given
+const ConfigName = "cinj.json"
+
+// Config holds the settings read from a cinj.json config file
+type Config struct {
+	// Plugins maps a file extension, such as ".dsl", or a file name to an
+	// external program that extracts snippets from those files
+	Plugins map[string]PluginConfig `json:"plugins"`
+	// Captions sets up the captions written with the snippets
+	Captions CaptionConfig `json:"captions"`
+	// Flavor is the renderer the output is written for: pandoc, mkdocs,
+	// vitepress or docusaurus. It sets how highlighted lines are marked
+	Flavor string `json:"flavor"`
+	// Redaction sets up the rules whose matches are replaced with <REDACTED>
+	// in every snippet
+	Redaction RedactionConfig `json:"redaction"`
+	// HTTP sets up how files given as http and https URLs are fetched
+	HTTP HTTPConfig `json:"http"`
+
+	// dir is the directory of the config file, relative paths inside of the
+	// config are resolved from it
+	dir string
+}
+
+// LoadConfig reads a config file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("Could not parse config %s: %w", path, err)
+	}
+	config.dir = filepath.Dir(path)
+
+	if err := checkFlavor(config.Flavor); err != nil {
+		return config, fmt.Errorf("Config %s: %w", path, err)
+	}
+
+	if err := config.Captions.check(); err != nil {
+		return config, fmt.Errorf("Config %s: %w", path, err)
+	}
+
+	if _, err := config.Redaction.detectors(false); err != nil {
+		return config, fmt.Errorf("Config %s: %w", path, err)
+	}
+
+	if _, err := config.HTTP.timeout(); err != nil {
+		return config, fmt.Errorf("HTTP settings in config %s: %w", path, err)
+	}
+
+	for pattern, plugin := range config.Plugins {
+		if len(plugin.Command) == 0 {
+			return config, fmt.Errorf("Plugin for %s in config %s has no command",
+				pattern, path)
+		}
+		if _, err := plugin.timeout(); err != nil {
+			return config, fmt.Errorf("Plugin for %s in config %s: %w",
+				pattern, path, err)
+		}
+	}
+
+	return config, nil
+}
+
+// FindConfig loads the cinj.json config file in the directory `dir`. An
+// empty config is returned when there is no config file
+func FindConfig(dir string) (Config, error) {
+	config, err := LoadConfig(filepath.Join(dir, ConfigName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{dir: dir}, nil
+	}
+	return config, err
+}
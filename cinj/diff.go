@@ -0,0 +1,284 @@
+package cinj
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// VerbDiff is the verb of a cinj command writing the unified diff of the
+// snippets of two files, as in cinj{diff ./v1/a.py ./v2/a.py --function=f}
+const VerbDiff = "diff"
+
+type diffArgs struct {
+	contextLines int
+}
+
+func newDiffArgs() *diffArgs {
+	return &diffArgs{
+		contextLines: 3,
+	}
+}
+
+// newDiffFlagSet returns the flag set used to parse the arguments of a diff
+// command into `args`. The other arguments are passed to the extractor of
+// both files
+func newDiffFlagSet(args *diffArgs) *flag.FlagSet {
+	diffFlag := flag.NewFlagSet("diffFlag", flag.ContinueOnError)
+	diffFlag.SetOutput(io.Discard)
+	diffFlag.IntVar(&args.contextLines, "context-lines", 3,
+		"Number of unchanged lines shown around every change of a diff")
+
+	return diffFlag
+}
+
+// editKind is the kind of an edit of a diff
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+// edit is a line of a diff. a and b are the indexes of the line in the old and
+// new lines, or of the line that follows for the side it is not part of
+type edit struct {
+	kind editKind
+	a    int
+	b    int
+}
+
+// diffSnippets extracts the same part of the two files of a diff command,
+// applies the transforms of the command to both and returns their unified
+// diff
+func (c Cinj) diffSnippets(cmd CinjCommand, render renderArgs) (Snippet, error) {
+	dArgs := newDiffArgs()
+	diffFlags := newDiffFlagSet(dArgs)
+	own, rest := splitFlags(diffFlags, cmd.Args)
+	if err := diffFlags.Parse(own); err != nil {
+		return Snippet{}, err
+	}
+	if dArgs.contextLines < 0 {
+		return Snippet{}, errors.New("The context-lines argument must be 0 or greater")
+	}
+
+	sides := make([]Snippet, len(cmd.Filepaths))
+	labels := make([]string, len(cmd.Filepaths))
+	for i, path := range cmd.Filepaths {
+		snippet, err := c.extract(path, cmd.revision(i), cmd.SHA256, rest)
+		if err != nil {
+			return Snippet{}, err
+		}
+		if len(snippet.Parts) > 0 {
+			return Snippet{}, fmt.Errorf("Can not diff %s, the snippet has more than one part", path)
+		}
+		sides[i], err = render.transform(snippet)
+		if err != nil {
+			return Snippet{}, err
+		}
+
+		labels[i] = path
+		if rel, err := filepath.Rel(filepath.Dir(c.Filepath), path); err == nil && filepath.IsLocal(rel) {
+			labels[i] = filepath.ToSlash(rel)
+		}
+		if cmd.revision(i) != "" {
+			labels[i] += "@" + cmd.revision(i)
+		}
+	}
+
+	content, err := unifiedDiff(labels[0], labels[1], sides[0], sides[1], dArgs.contextLines)
+	if err != nil {
+		return Snippet{}, err
+	}
+	return Snippet{Content: content, Language: Diff, Name: sides[1].Name}, nil
+}
+
+// unifiedDiff returns the unified diff of two snippets, with `context`
+// unchanged lines around every change. The hunk headers count lines from
+// the source lines of the snippets when they are known. Snippets that are
+// the same only give the file headers
+func unifiedDiff(oldLabel string, newLabel string, before Snippet, after Snippet,
+	context int,
+) (string, error) {
+	a, aNumbers := diffLines(before)
+	b, bNumbers := diffLines(after)
+	edits, err := myersDiff(a, b)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldLabel, newLabel)
+
+	i := 0
+	for i < len(edits) {
+		if edits[i].kind == editEqual {
+			i++
+			continue
+		}
+
+		// a hunk goes on until a run of more than twice the context of
+		// unchanged lines
+		start := max(i-context, 0)
+		last := i
+		for j := i; j < len(edits); {
+			if edits[j].kind != editEqual {
+				last = j
+				j++
+				continue
+			}
+			run := 0
+			for j+run < len(edits) && edits[j+run].kind == editEqual {
+				run++
+			}
+			if j+run >= len(edits) || run > 2*context {
+				break
+			}
+			j += run
+		}
+		end := min(last+context+1, len(edits))
+
+		hunk := edits[start:end]
+		aCount, bCount := 0, 0
+		for _, e := range hunk {
+			if e.kind != editInsert {
+				aCount++
+			}
+			if e.kind != editDelete {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(aNumbers, hunk[0].a, aCount), hunkRange(bNumbers, hunk[0].b, bCount))
+		for _, e := range hunk {
+			switch e.kind {
+			case editEqual:
+				sb.WriteString(" " + a[e.a] + "\n")
+			case editDelete:
+				sb.WriteString("-" + a[e.a] + "\n")
+			case editInsert:
+				sb.WriteString("+" + b[e.b] + "\n")
+			}
+		}
+		i = end
+	}
+	return sb.String(), nil
+}
+
+// diffLines splits the content of a snippet into lines, along with the
+// source line of every line, or nil when they are not known
+func diffLines(snippet Snippet) ([]string, []int) {
+	if snippet.Content == "" {
+		return []string{}, nil
+	}
+	lines := strings.Split(strings.TrimSuffix(snippet.Content, "\n"), "\n")
+	numbers, err := snippet.sourceLines()
+	if err != nil || len(numbers) != len(lines) {
+		return lines, nil
+	}
+	for _, n := range numbers {
+		if n == 0 {
+			return lines, nil
+		}
+	}
+	return lines, numbers
+}
+
+// hunkRange writes the start and line count of one side of a hunk, such as
+// 12,7. An empty side starts at the line before it, as in diff -u
+func hunkRange(numbers []int, idx int, count int) string {
+	start := idx + 1
+	if numbers != nil && idx < len(numbers) {
+		start = numbers[idx]
+	} else if numbers != nil && len(numbers) > 0 {
+		start = numbers[len(numbers)-1] + 1
+	}
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// maxDiffEdits is the largest number of deleted and inserted lines of a
+// diff. The memory used to find the edits grows with its square
+const maxDiffEdits = 4000
+
+// myersDiff returns the shortest list of edits turning the lines `a` into
+// the lines `b`, with Myers' O(ND) algorithm. The deletions of a change come
+// before its insertions. It fails when more than maxDiffEdits lines change
+func myersDiff(a []string, b []string) ([]edit, error) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace keeps the diagonals -d-1 to d+1 of v as they were before every
+	// step d, the only ones read to walk the path back
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		if d > maxDiffEdits {
+			return nil, fmt.Errorf("Can not diff snippets with more than %d changed lines",
+				maxDiffEdits)
+		}
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	reversed := []edit{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// band[k+d+1] is diagonal k of v before step d
+		band := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && band[k+d] < band[k+d+2]) {
+			prevK = k + 1
+		}
+		prevX := band[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{editEqual, x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{editInsert, x, prevY})
+			} else {
+				reversed = append(reversed, edit{editDelete, prevX, y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits, nil
+}
@@ -0,0 +1,97 @@
+package cinj
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMyersDiff(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected string
+	}{
+		{"a b c a b b a", "c b a b a c", "-a -b  c +b  a  b -b  a +c"},
+		{"", "x y", "+x +y"},
+		{"x y", "", "-x -y"},
+		{"x y", "x y", " x  y"},
+	}
+
+	for i, tt := range tests {
+		edits, err := myersDiff(strings.Fields(tt.a), strings.Fields(tt.b))
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		got := []string{}
+		for _, e := range edits {
+			switch e.kind {
+			case editEqual:
+				got = append(got, " "+a[e.a])
+			case editDelete:
+				got = append(got, "-"+a[e.a])
+			case editInsert:
+				got = append(got, "+"+b[e.b])
+			}
+		}
+		if strings.Join(got, " ") != tt.expected {
+			t.Fatalf("tests[%d] - expected %q, got %q", i, tt.expected, strings.Join(got, " "))
+		}
+	}
+
+	// large inputs with few changes are diffed, too many changes are an error
+	a, b := make([]string, 50000), make([]string, 50000)
+	for i := range a {
+		a[i] = fmt.Sprint(i)
+		b[i] = fmt.Sprint(i)
+	}
+	b[100], b[40000] = "x", "y"
+	edits, err := myersDiff(a, b)
+	if err != nil || len(edits) != 50002 {
+		t.Fatalf("expected 50002 edits, got %d (%v)", len(edits), err)
+	}
+	for i := range b {
+		b[i] = "z" + b[i]
+	}
+	if _, err := myersDiff(a, b); err == nil || !strings.Contains(err.Error(), "changed lines") {
+		t.Fatalf("expected an error for too many changes, got %v", err)
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"v1/handler.py": "import os\n\n\ndef handle(req):\n    a = 1\n    b = 2\n    c = 3\n    d = 4\n    e = 5\n    f = 6\n    g = 7\n    return a\n",
+		"v2/handler.py": "import os\nimport sys\n\n\ndef handle(req):\n    a = 1\n    b = 2\n    c = 3\n    d = 4\n    e = 5\n    f = 6\n    g = 8\n    return a\n",
+		"report.cinj":   "cinj{diff ./v1/handler.py ./v2/handler.py --function=handle --context-lines=1}\n\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	c := Cinj{Filepath: filepath.Join(dir, "report.cinj"), Newname: filepath.Join(dir, "report.md")}
+	if err := c.Run(); err != nil {
+		t.Fatal(err.Error())
+	}
+	got, err := os.ReadFile(c.Newname)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "```diff\n--- v1/handler.py\n+++ v2/handler.py\n@@ -10,3 +11,3 @@\n     f = 6\n-    g = 7\n+    g = 8\n     return a\n```\n"
+	if string(got) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	if _, err := c.getCinjCommand("cinj{diff ./v1/handler.py --function=handle}"); err == nil {
+		t.Fatal("expected an error for a diff of a single file")
+	}
+}
@@ -0,0 +1,250 @@
+package cinj
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TheDavo/cinj/lexers/clike"
+	"github.com/TheDavo/cinj/lexers/python"
+)
+
+// Markers of a region, as comments such as `# region setup` and
+// `# endregion`, or `#region setup` in C#
+var (
+	regionStart = regexp.MustCompile(`^\s*(?:#|//|/\*|<!--|--|;)\s*#?region\b[ \t]*(.*?)[ \t]*(?:\*/|-->)?[ \t]*$`)
+	regionEnd   = regexp.MustCompile(`^\s*(?:#|//|/\*|<!--|--|;)\s*#?end-?region\b`)
+)
+
+// functionKinds are the clike declaration kinds whose bodies --elide-bodies
+// collapses
+var functionKinds = map[string]bool{
+	"function_declaration":    true,
+	"method_declaration":      true,
+	"func_literal":            true,
+	"function_expression":     true,
+	"arrow_function":          true,
+	"method_definition":       true,
+	"constructor_declaration": true,
+	"function_definition":     true,
+	"function_item":           true,
+}
+
+// elision is a range of lines of a snippet, counting from 0 with the end
+// included, replaced with a placeholder
+type elision struct {
+	start int
+	end   int
+}
+
+// commentFor returns the comment delimiters of a language, used to write the
+// placeholder of elided lines. Both are empty for languages without a known
+// comment syntax
+func commentFor(language Filetype) (string, string) {
+	if cLanguage, ok := clike.ForName(string(language)); ok {
+		return cLanguage.LineComment, ""
+	}
+	switch language {
+	case Python, Pycon, "sh", "bash", "ruby", "yaml", "toml", "r":
+		return "#", ""
+	case "sql", "lua", "haskell":
+		return "--", ""
+	case "css":
+		return "/*", "*/"
+	case "html", "xml", Markdown:
+		return "<!--", "-->"
+	}
+	return "", ""
+}
+
+// elide replaces the lines given with --elide, the regions given with
+// --elide-region and, with --elide-bodies, the bodies of the functions nested
+// in the snippet with a placeholder such as `# ... 29 lines omitted ...`
+func (r renderArgs) elide(snippet Snippet) (Snippet, error) {
+	if r.elideLines == "" && len(r.elideRegion) == 0 && !r.elideBodies {
+		return snippet, nil
+	}
+
+	newline := strings.HasSuffix(snippet.Content, "\n")
+	lines := strings.Split(strings.TrimSuffix(snippet.Content, "\n"), "\n")
+	elisions := []elision{}
+	markers := map[int]bool{} // region marker lines, not counted as omitted
+
+	listed, err := parseLineList(r.elideLines)
+	if err != nil {
+		return snippet, err
+	}
+	for _, interval := range listed {
+		if interval.last > len(lines) {
+			return snippet, fmt.Errorf("Can not elide line %d, the snippet has %d lines",
+				interval.last, len(lines))
+		}
+		elisions = append(elisions, elision{interval.first - 1, interval.last - 1})
+	}
+
+	for _, name := range r.elideRegion {
+		regions := regionElisions(lines, name, markers)
+		if len(regions) == 0 {
+			return snippet, fmt.Errorf("Could not find region %s", name)
+		}
+		elisions = append(elisions, regions...)
+	}
+
+	if r.elideBodies {
+		bodies, err := bodyElisions(snippet)
+		if err != nil {
+			return snippet, err
+		}
+		elisions = append(elisions, bodies...)
+	}
+
+	numbers, err := snippet.sourceLines()
+	if err != nil || len(numbers) != len(lines) {
+		numbers = nil
+	}
+
+	openComment, closeComment := commentFor(snippet.Language)
+	kept := []string{}
+	keptNumbers := []int{}
+	next := 0
+	for _, e := range mergeElisions(elisions) {
+		for ; next < e.start; next++ {
+			kept = append(kept, lines[next])
+			if numbers != nil {
+				keptNumbers = append(keptNumbers, numbers[next])
+			}
+		}
+
+		omitted := 0
+		indent := ""
+		found := false
+		for i := e.start; i <= e.end; i++ {
+			if markers[i] {
+				continue
+			}
+			omitted++
+			if !found && strings.TrimSpace(lines[i]) != "" {
+				indent = lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+				found = true
+			}
+		}
+		if !found {
+			line := lines[e.start]
+			indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		}
+
+		kept = append(kept, indent+placeholder(omitted, openComment, closeComment))
+		keptNumbers = append(keptNumbers, 0)
+		next = e.end + 1
+	}
+	for ; next < len(lines); next++ {
+		kept = append(kept, lines[next])
+		if numbers != nil {
+			keptNumbers = append(keptNumbers, numbers[next])
+		}
+	}
+
+	snippet.Content = strings.Join(kept, "\n")
+	if newline {
+		snippet.Content += "\n"
+	}
+	if numbers != nil {
+		snippet.LineNumbers = keptNumbers
+	}
+	return snippet, nil
+}
+
+// placeholder returns the text written in place of elided lines
+func placeholder(omitted int, openComment string, closeComment string) string {
+	text := fmt.Sprintf("... %d lines omitted ...", omitted)
+	if omitted == 1 {
+		text = "... 1 line omitted ..."
+	}
+	if openComment != "" {
+		text = openComment + " " + text
+	}
+	if closeComment != "" {
+		text += " " + closeComment
+	}
+	return text
+}
+
+// mergeElisions sorts elisions and joins the ones that overlap or touch
+func mergeElisions(elisions []elision) []elision {
+	sort.Slice(elisions, func(i, j int) bool { return elisions[i].start < elisions[j].start })
+	merged := []elision{}
+	for _, e := range elisions {
+		if last := len(merged) - 1; last >= 0 && e.start <= merged[last].end+1 {
+			merged[last].end = max(merged[last].end, e.end)
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// regionElisions returns the regions called `name`, from their start marker
+// to their end marker. The marker lines are added to `markers`
+func regionElisions(lines []string, name string, markers map[int]bool) []elision {
+	elisions := []elision{}
+	for i := 0; i < len(lines); i++ {
+		m := regionStart.FindStringSubmatch(lines[i])
+		if m == nil || m[1] != name {
+			continue
+		}
+
+		depth := 0
+		for j := i + 1; j < len(lines); j++ {
+			if regionEnd.MatchString(lines[j]) {
+				if depth == 0 {
+					elisions = append(elisions, elision{i, j})
+					markers[i], markers[j] = true, true
+					i = j
+					break
+				}
+				depth--
+			} else if regionStart.MatchString(lines[j]) {
+				depth++
+			}
+		}
+	}
+	return elisions
+}
+
+// bodyElisions returns the bodies of the functions nested in the snippet,
+// such as the methods of a class, found with the lexer of its language
+func bodyElisions(snippet Snippet) ([]elision, error) {
+	elisions := []elision{}
+
+	if snippet.Language == Python {
+		pl := python.NewLexer(snippet.Content, 4)
+		pl.Lex()
+		for _, def := range pl.Functions() {
+			if len(def.Scopes) > 0 && def.BodyLine > 0 && def.EndLine >= def.BodyLine {
+				elisions = append(elisions, elision{def.BodyLine - 1, def.EndLine - 1})
+			}
+		}
+	} else if cLanguage, ok := clike.ForName(string(snippet.Language)); ok {
+		var walk func(node *clike.Node, depth int)
+		walk = func(node *clike.Node, depth int) {
+			if depth > 1 && functionKinds[node.Kind] && node.EndLine-node.BodyLine >= 2 {
+				// the lines between the braces
+				elisions = append(elisions, elision{node.BodyLine, node.EndLine - 2})
+			}
+			for _, child := range node.Children {
+				walk(child, depth+1)
+			}
+		}
+		walk(clike.Parse(snippet.Content, cLanguage), 0)
+	} else {
+		return nil, fmt.Errorf("Can not elide function bodies of %s snippets",
+			snippet.Language)
+	}
+
+	if len(elisions) == 0 {
+		return nil, errors.New("The snippet has no nested function bodies to elide")
+	}
+	return elisions, nil
+}
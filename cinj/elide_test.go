@@ -0,0 +1,58 @@
+package cinj
+
+import (
+	"testing"
+)
+
+func TestElide(t *testing.T) {
+	class := "class A:\n    def f(self):\n        x = 1\n        return x\n\n    def g(self): pass\n"
+	javaClass := "class A {\n    void f() {\n        int x = 1;\n        run(x);\n    }\n}\n"
+	region := "a = 1\n# region setup\nb = 2\nc = 3\n# endregion\nd = 4\n"
+
+	tests := []struct {
+		snippet  Snippet
+		args     []string
+		expected string
+	}{
+		{Snippet{Content: class, Language: Python}, []string{"--elide-bodies"},
+			"class A:\n    def f(self):\n        # ... 2 lines omitted ...\n\n    def g(self): pass\n"},
+		{Snippet{Content: javaClass, Language: "java"}, []string{"--elide-bodies"},
+			"class A {\n    void f() {\n        // ... 2 lines omitted ...\n    }\n}\n"},
+		{Snippet{Content: region, Language: Python}, []string{"--elide-region=setup"},
+			"a = 1\n# ... 2 lines omitted ...\nd = 4\n"},
+		{Snippet{Content: region, Language: "css"}, []string{"--elide=1,3-4"},
+			"/* ... 1 line omitted ... */\n# region setup\n/* ... 2 lines omitted ... */\n# endregion\nd = 4\n"},
+		{Snippet{Content: region, Language: Plain}, []string{"--elide=6", "--elide-region=setup"},
+			"a = 1\n... 3 lines omitted ...\n"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := r.elide(tt.snippet)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i, tt.expected, got.Content)
+		}
+	}
+
+	r, _ := parseRenderArgs([]string{"--elide=2-3"})
+	got, _ := r.elide(Snippet{Content: region, Language: Python, StartLine: 10})
+	expected := []int{10, 0, 13, 14, 15}
+	for i, line := range expected {
+		if got.LineNumbers[i] != line {
+			t.Fatalf("expected line numbers %v, got %v", expected, got.LineNumbers)
+		}
+	}
+
+	for _, args := range [][]string{{"--elide=9"}, {"--elide=2-999999999"}, {"--elide-region=missing"}, {"--elide-bodies"}} {
+		r, _ := parseRenderArgs(args)
+		if _, err := r.elide(Snippet{Content: region, Language: "markdown"}); err == nil {
+			t.Fatalf("%v - expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package cinj
+
+import "fmt"
+
+// DirectiveError is returned when a cinj command inside of the source file
+// cannot be carried out. It records where the command is so the user can
+// find it
+type DirectiveError struct {
+	Line      int
+	Directive string
+	Err       error
+}
+
+func (e *DirectiveError) Error() string {
+	return fmt.Sprintf("Error on line: %d %s\nError: %s", e.Line, e.Directive,
+		e.Err.Error())
+}
+
+func (e *DirectiveError) Unwrap() error {
+	return e.Err
+}
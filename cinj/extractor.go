@@ -0,0 +1,180 @@
+package cinj
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source is a file included by a cinj command
+type Source struct {
+	Path    string
+	Content []byte
+}
+
+// Extractor grabs content out of the files of one language, based on the
+// arguments of a cinj command. Extractors for new languages are added with
+// RegisterExtractor, including from outside of this module
+type Extractor interface {
+	// FileType is the language of the code blocks the extractor writes
+	FileType() Filetype
+	// Flags lists the names of the arguments the extractor supports,
+	// without the leading dashes. A nil list lets every argument through
+	Flags() []string
+	// Extract returns the part of the source chosen by the arguments
+	Extract(src Source, args []string) (Snippet, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Extractor{}
+)
+
+// RegisterExtractor makes an extractor available for the files matching any
+// of the patterns. A pattern starting with a '.' is an extension such as
+// ".py" or ".tar.gz", any other pattern is a full file name such as
+// "Makefile". Registering a pattern again replaces the previous extractor
+func RegisterExtractor(e Extractor, patterns ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, pattern := range patterns {
+		registry[strings.ToLower(pattern)] = e
+	}
+}
+
+// LookupExtractor returns the extractor registered for a file path. File
+// names are matched first, then the longest matching extension
+func LookupExtractor(path string) (Extractor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return matchPattern(registry, path)
+}
+
+// matchPattern returns the value of the pattern matching a file path, where
+// patterns are either file names or extensions. File names are matched
+// first, then the longest matching extension
+func matchPattern[T any](patterns map[string]T, path string) (T, bool) {
+	base := strings.ToLower(filepath.Base(path))
+	if v, ok := patterns[base]; ok {
+		return v, true
+	}
+
+	for i := 0; i < len(base); i++ {
+		if base[i] != '.' {
+			continue
+		}
+		if v, ok := patterns[base[i:]]; ok {
+			return v, true
+		}
+	}
+
+	var none T
+	return none, false
+}
+
+// extractorFor returns the extractor for a file path. Plugins in the config
+// are used first, then the registered extractors, and an extractor that
+// returns the whole file when neither match
+func (c Cinj) extractorFor(path string) Extractor {
+	if isURL(path) {
+		path = urlName(path)
+	}
+	plugins := map[string]PluginConfig{}
+	for pattern, plugin := range c.Config.Plugins {
+		plugins[strings.ToLower(pattern)] = plugin
+	}
+	if plugin, ok := matchPattern(plugins, path); ok {
+		return pluginExtractor{config: plugin, dir: c.Config.dir}
+	}
+
+	return extractorFor(path)
+}
+
+// extractorFor returns the extractor registered for a file path, or an
+// extractor that returns the whole file when none are registered
+func extractorFor(path string) Extractor {
+	if e, ok := LookupExtractor(path); ok {
+		return e
+	}
+	return plainExtractor{language: Plain}
+}
+
+// checkFlags returns an error naming the first argument that the extractor
+// does not support
+func checkFlags(e Extractor, args []string) error {
+	if e.Flags() == nil {
+		return nil
+	}
+
+	supported := map[string]bool{}
+	for _, name := range e.Flags() {
+		supported[name] = true
+	}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !supported[name] {
+			names := append([]string{}, e.Flags()...)
+			sort.Strings(names)
+			if len(names) == 0 {
+				return fmt.Errorf("Unknown argument %s, %s files do not take arguments",
+					arg, describeFileType(e.FileType()))
+			}
+			return fmt.Errorf("Unknown argument %s for %s files, expected one of --%s",
+				arg, describeFileType(e.FileType()), strings.Join(names, ", --"))
+		}
+	}
+
+	return nil
+}
+
+// flagNames returns the names of every flag defined in a flag set, used by
+// extractors built on the flag package to implement Flags
+func flagNames(fs *flag.FlagSet) []string {
+	names := []string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// describeFileType names a file type for error messages
+func describeFileType(ft Filetype) string {
+	if ft == Plain {
+		return "plain"
+	}
+	return ft.String()
+}
+
+// plainExtractor returns the whole content of a file, used for the file
+// types that do not have a dedicated extractor
+type plainExtractor struct {
+	language Filetype
+}
+
+func (e plainExtractor) FileType() Filetype {
+	return e.language
+}
+
+func (e plainExtractor) Flags() []string {
+	return []string{}
+}
+
+func (e plainExtractor) Extract(src Source, args []string) (Snippet, error) {
+	return wholeFile(src, e.language), nil
+}
+
+func init() {
+	RegisterExtractor(pythonExtractor{}, ".py", ".pyi")
+	RegisterExtractor(notebookExtractor{}, ".ipynb")
+	RegisterExtractor(plainExtractor{language: Text}, ".txt")
+	RegisterExtractor(plainExtractor{language: Markdown}, ".md")
+}
@@ -0,0 +1,86 @@
+package cinj
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type upperExtractor struct{}
+
+func (upperExtractor) FileType() Filetype {
+	return "shout"
+}
+
+func (upperExtractor) Flags() []string {
+	return []string{"first-line"}
+}
+
+func (upperExtractor) Extract(src Source, args []string) (Snippet, error) {
+	content := strings.ToUpper(string(src.Content))
+	if len(args) > 0 && args[0] == "--first-line" {
+		content, _, _ = strings.Cut(content, "\n")
+	}
+	return Snippet{Content: content, Language: "shout"}, nil
+}
+
+func TestLookupExtractor(t *testing.T) {
+	RegisterExtractor(upperExtractor{}, ".shout", ".tar.shout", "Shoutfile")
+
+	tests := []struct {
+		path     string
+		expected Filetype
+	}{
+		{"./src/main.py", Python},
+		{"./notebooks/analysis.ipynb", Notebook},
+		{"./a.shout", "shout"},
+		{"./b.tar.shout", "shout"},
+		{"./dir/Shoutfile", "shout"},
+		{"./unknown.xyz", Plain},
+	}
+
+	for i, test := range tests {
+		if got := extractorFor(test.path).FileType(); got != test.expected {
+			t.Fatalf("tests[%d]: expected file type %q for %s, got %q",
+				i, test.expected, test.path, got)
+		}
+	}
+}
+
+func TestRunWithRegisteredExtractor(t *testing.T) {
+	RegisterExtractor(upperExtractor{}, ".shout")
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "a.shout"), []byte("hello\nworld\n"), 0o644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	src := filepath.Join(dir, "report.cinj")
+	err = os.WriteFile(src, []byte("before\ncinj{./a.shout --first-line}\n\nafter\n"), 0o644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	c := Cinj{Filepath: src, Newname: filepath.Join(dir, "report.md")}
+	if err := c.Run(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	got, err := os.ReadFile(c.Newname)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "before\n```shout\nHELLO\n```\nafter\n"
+	if string(got) != expected {
+		t.Fatalf("Expected \n%s\nGot \n%s", expected, got)
+	}
+
+	err = os.WriteFile(src, []byte("cinj{./a.shout --loud}\n"), 0o644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := c.Run(); err == nil || !strings.Contains(err.Error(), "--first-line") {
+		t.Fatalf("expected an unknown argument error listing --first-line, got %v", err)
+	}
+}
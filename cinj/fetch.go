@@ -0,0 +1,150 @@
+package cinj
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultFetchTimeout is how long fetching a file can take when the config
+// does not set a timeout
+const defaultFetchTimeout = 30 * time.Second
+
+// HTTPConfig sets up how files given as http and https URLs are fetched
+type HTTPConfig struct {
+	// CacheDir is where fetched files are kept. A relative path is resolved
+	// from the directory of the config file, defaulting to cinj/http in the
+	// user cache directory
+	CacheDir string `json:"cache_dir"`
+	// Offline reads files from the cache only, without fetching them
+	Offline bool `json:"offline"`
+	// Timeout is a duration such as "5s", defaulting to 30 seconds
+	Timeout string `json:"timeout"`
+}
+
+func (h HTTPConfig) timeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return defaultFetchTimeout, nil
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %s", h.Timeout)
+	}
+	return timeout, nil
+}
+
+// cacheDir returns the directory of the cache, resolving a relative path
+// from `dir`
+func (h HTTPConfig) cacheDir(dir string) (string, error) {
+	if h.CacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("No cache directory for fetched files: %w", err)
+		}
+		return filepath.Join(userCache, "cinj", "http"), nil
+	}
+	if filepath.IsAbs(h.CacheDir) {
+		return h.CacheDir, nil
+	}
+	return filepath.Join(dir, h.CacheDir), nil
+}
+
+// isURL reports whether the path of a cinj command is an http or https URL
+func isURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// urlName returns the path of a URL without its query, used to find the
+// extractor of a fetched file
+func urlName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return path.Base(u.Path)
+}
+
+// fetch returns the content of a URL. Fetched files are kept in the cache,
+// which is used instead of the network in offline mode, or when the file in
+// the cache matches `sum`, the expected SHA-256 of the content. A fetched
+// file that does not match `sum` is not kept
+func (c Cinj) fetch(rawURL string, sum string) ([]byte, error) {
+	dir, err := c.Config.HTTP.cacheDir(c.Config.dir)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(rawURL))
+	cached := filepath.Join(dir, hex.EncodeToString(key[:]))
+
+	content, err := os.ReadFile(cached)
+	if c.Config.HTTP.Offline {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("Can not fetch %s in offline mode, it is not in the cache", rawURL)
+		}
+		return content, err
+	}
+	if err == nil && sum != "" && checkSum(content, sum) == nil {
+		return content, nil
+	}
+
+	timeout, err := c.Config.HTTP.timeout()
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("Could not fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not fetch %s: %s", rawURL, resp.Status)
+	}
+	content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not fetch %s: %w", rawURL, err)
+	}
+
+	if sum != "" {
+		if err := checkSum(content, sum); err != nil {
+			return nil, fmt.Errorf("%s: %w", rawURL, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cached, content, 0o644); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// checkSum returns an error when the SHA-256 of content is not the hex
+// encoded sum
+func checkSum(content []byte, sum string) error {
+	got := sha256.Sum256(content)
+	if hex.EncodeToString(got[:]) != strings.ToLower(sum) {
+		return fmt.Errorf("SHA-256 mismatch, expected %s but the content has %s",
+			strings.ToLower(sum), hex.EncodeToString(got[:]))
+	}
+	return nil
+}
+
+// checkSumFormat returns an error when a --sha256 value is not 64 hex
+// characters
+func checkSumFormat(sum string) error {
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != 2*sha256.Size {
+		return fmt.Errorf("Invalid SHA-256 %s, expected 64 hex characters", sum)
+	}
+	return nil
+}
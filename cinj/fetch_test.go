@@ -0,0 +1,77 @@
+package cinj
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	content := "def x():\n    return 1\n"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/lib/file.py" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256([]byte(content))
+	pinned := hex.EncodeToString(sum[:])
+	wrong := strings.Repeat("0", 64)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "report.cinj")
+	c := Cinj{
+		Filepath: src,
+		Newname:  filepath.Join(dir, "report.md"),
+		Config:   Config{HTTP: HTTPConfig{CacheDir: filepath.Join(dir, "cache")}},
+	}
+	run := func(directive string) (string, error) {
+		if err := os.WriteFile(src, []byte(directive+"\n\n"), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := c.Run(); err != nil {
+			return "", err
+		}
+		got, err := os.ReadFile(c.Newname)
+		return string(got), err
+	}
+
+	expected := "```python\ndef x():\n    return 1\n```\n"
+	got, err := run("cinj{" + server.URL + "/lib/file.py?raw=1 --function=x --sha256=" + pinned + "}")
+	if err != nil || got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s (%v)", expected, got, err)
+	}
+
+	// pinned files in the cache are not fetched again
+	if _, err := run("cinj{" + server.URL + "/lib/file.py?raw=1 --sha256=" + pinned + "}"); err != nil || requests != 1 {
+		t.Fatalf("expected the cached file to be used, got %d requests (%v)", requests, err)
+	}
+
+	_, err = run("cinj{" + server.URL + "/lib/file.py --sha256=" + wrong + "}")
+	if err == nil || !strings.Contains(err.Error(), "SHA-256 mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	if _, err := run("cinj{" + server.URL + "/missing.py}"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	c.Config.HTTP.Offline = true
+	requests = 0
+	if got, err := run("cinj{" + server.URL + "/lib/file.py?raw=1 --function=x}"); err != nil || got != expected || requests != 0 {
+		t.Fatalf("expected the cached file offline, got %q with %d requests (%v)", got, requests, err)
+	}
+	if _, err := run("cinj{" + server.URL + "/other.py}"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Fatalf("expected an offline error, got %v", err)
+	}
+}
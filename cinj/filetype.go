@@ -8,8 +8,11 @@ func (ft Filetype) String() string {
 
 const (
 	Python     Filetype = "python"
+	Pycon               = "pycon"
 	Javascript          = "javascript"
 	Markdown            = "md"
+	Notebook            = "ipynb"
+	Diff                = "diff"
 	Text                = ""
 	Plain               = ""
 )
@@ -0,0 +1,463 @@
+package cinj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Output formats of the new file
+const (
+	FormatMarkdown    = "markdown"
+	FormatAsciiDoc    = "asciidoc"
+	FormatRST         = "rst"
+	FormatLaTeX       = "latex"        // lstlisting environments
+	FormatLaTeXMinted = "latex-minted" // minted environments
+	FormatOrg         = "org"
+	FormatHTML        = "html" // standalone HTML rendered from Markdown
+)
+
+// latexCaptionFormat leaves out the listing number, which LaTeX adds to the
+// captions of listings itself
+const latexCaptionFormat = "{{if .Name}}{{code .Name}} — {{end}}" +
+	"{{.Path}}{{if .Commit}} at {{.Commit}}{{end}}{{if .Lines}}, lines {{.Lines}}{{end}}"
+
+// outputFormat writes code blocks in the markup of one output format
+type outputFormat struct {
+	// extension of the new file
+	extension string
+	// inputs are the extensions of the files written in this format, after
+	// the .cinj part of the name
+	inputs []string
+	// writeBlock returns the markup of a code block
+	writeBlock func(block codeBlock) (string, error)
+	// code formats an inline code span for captions
+	code func(s string) string
+	// escape escapes text placed into the markup, such as the fields of a
+	// caption. It is nil for formats that do not need escaping
+	escape func(s string) string
+	// captionFormat is the default caption format
+	captionFormat string
+	// document turns the whole written text into the new file, it is nil
+	// for formats written as they are. `name` is the name of the new file
+	// without its extension
+	document func(text string, name string) string
+	// lineGutter formats show line numbers next to the code themselves, so
+	// --line-numbers does not add them to the content
+	lineGutter bool
+}
+
+var outputFormats = map[string]outputFormat{
+	FormatMarkdown: {
+		extension:     ".md",
+		inputs:        []string{"", ".md"},
+		writeBlock:    markdownBlock,
+		code:          func(s string) string { return "`" + s + "`" },
+		captionFormat: defaultCaptionFormat,
+	},
+	FormatAsciiDoc: {
+		extension:     ".adoc",
+		inputs:        []string{".adoc", ".asciidoc"},
+		writeBlock:    asciidocBlock,
+		code:          func(s string) string { return "`+" + s + "+`" },
+		captionFormat: defaultCaptionFormat,
+	},
+	FormatRST: {
+		extension:     ".rst",
+		inputs:        []string{".rst"},
+		writeBlock:    rstBlock,
+		code:          func(s string) string { return "``" + s + "``" },
+		escape:        func(s string) string { return strings.Join(strings.Fields(s), " ") },
+		captionFormat: defaultCaptionFormat,
+	},
+	FormatLaTeX: {
+		extension:     ".tex",
+		inputs:        []string{".tex"},
+		writeBlock:    lstlistingBlock,
+		code:          func(s string) string { return `\texttt{` + s + `}` },
+		escape:        escapeLaTeX,
+		captionFormat: latexCaptionFormat,
+	},
+	FormatLaTeXMinted: {
+		extension:     ".tex",
+		writeBlock:    mintedBlock,
+		code:          func(s string) string { return `\texttt{` + s + `}` },
+		escape:        escapeLaTeX,
+		captionFormat: latexCaptionFormat,
+	},
+	FormatOrg: {
+		extension:     ".org",
+		inputs:        []string{".org"},
+		writeBlock:    orgBlock,
+		code:          func(s string) string { return "=" + s + "=" },
+		escape:        func(s string) string { return strings.Join(strings.Fields(s), " ") },
+		captionFormat: defaultCaptionFormat,
+	},
+	FormatHTML: {
+		extension:     ".html",
+		writeBlock:    htmlBlock,
+		code:          func(s string) string { return "<code>" + s + "</code>" },
+		escape:        html.EscapeString,
+		captionFormat: defaultCaptionFormat,
+		document:      htmlDocument,
+		lineGutter:    true,
+	},
+}
+
+// checkFormat returns an error for an unknown output format. An empty format
+// is Markdown
+func checkFormat(format string) error {
+	if _, ok := outputFormats[format]; ok || format == "" {
+		return nil
+	}
+	return fmt.Errorf("Unknown output format %s, expected %s, %s, %s, %s, %s, %s or %s",
+		format, FormatMarkdown, FormatAsciiDoc, FormatRST, FormatLaTeX,
+		FormatLaTeXMinted, FormatOrg, FormatHTML)
+}
+
+// FormatExtension returns the extension of the files written in an output
+// format, such as ".adoc"
+func FormatExtension(format string) (string, error) {
+	if err := checkFormat(format); err != nil {
+		return "", err
+	}
+	if format == "" {
+		format = FormatMarkdown
+	}
+	return outputFormats[format].extension, nil
+}
+
+// FormatForPath returns the output format of a file to work on, found from
+// its extension such as .cinj.md or .cinj.adoc, and the path without that
+// extension
+func FormatForPath(path string) (string, string, error) {
+	base := filepath.Base(path)
+	idx := strings.LastIndex(strings.ToLower(base), ".cinj")
+	if idx < 0 {
+		return "", "", errors.New("No appropriate file extension found")
+	}
+
+	rest := strings.ToLower(base[idx+len(".cinj"):])
+	for name, format := range outputFormats {
+		for _, input := range format.inputs {
+			if input == rest {
+				return name, strings.TrimSuffix(path, base[idx:]), nil
+			}
+		}
+	}
+
+	return "", "", errors.New("No appropriate file extension found")
+}
+
+// format returns the output format the new file is written in
+func (c *Cinj) format() outputFormat {
+	if format, ok := outputFormats[c.Format]; ok {
+		return format
+	}
+	return outputFormats[FormatMarkdown]
+}
+
+// contentLines splits the content of a snippet into lines, without their
+// line endings
+func contentLines(content string) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// markdownBlock writes a fenced code block, with its caption as a paragraph
+// above or below it
+func markdownBlock(block codeBlock) (string, error) {
+	var sb strings.Builder
+	if block.caption != "" && !block.captionBelow {
+		sb.WriteString(block.caption + "\n\n")
+	}
+
+	fence := block.render.fenceFor(block.Content)
+	if !block.Raw {
+		sb.WriteString(fence + block.render.infoString(block) + "\n")
+	}
+	for _, line := range contentLines(block.Content) {
+		sb.WriteString(line + "\n")
+	}
+	if !block.Raw {
+		sb.WriteString(fence + "\n")
+	}
+
+	if block.caption != "" && block.captionBelow {
+		sb.WriteString("\n" + block.caption + "\n\n")
+	}
+	return sb.String(), nil
+}
+
+// asciidocCallout matches a line ending with a callout, such as <1>, which
+// AsciiDoc would turn into a callout marker
+var asciidocCallout = regexp.MustCompile(`<(\d+|\.|!--\d+--)>\s*$`)
+
+// asciidocBlock writes a [source] listing block. The caption is the block
+// title, which AsciiDoc always places above the block
+func asciidocBlock(block codeBlock) (string, error) {
+	var sb strings.Builder
+	if block.render.fenceID != "" {
+		sb.WriteString("[[" + block.render.fenceID + "]]\n")
+	}
+	if block.caption != "" {
+		sb.WriteString("." + block.caption + "\n")
+	}
+
+	attrs := []string{"source"}
+	if block.Language != Plain {
+		attrs = append(attrs, block.Language.String())
+	}
+	if block.startFrom > 0 {
+		attrs = append(attrs, "linenums", fmt.Sprintf("start=%d", block.startFrom))
+	}
+	if len(block.highlighted) > 0 {
+		attrs = append(attrs,
+			`highlight="`+formatLineRanges(block.highlighted, ",", "..")+`"`)
+	}
+
+	lines := contentLines(block.Content)
+	delimiter := "----"
+	for _, line := range lines {
+		if strings.Trim(line, "-") == "" && len(line) >= len(delimiter) {
+			delimiter = strings.Repeat("-", len(line)+1)
+		}
+	}
+
+	if !block.Raw {
+		sb.WriteString("[" + strings.Join(attrs, ",") + "]\n" + delimiter + "\n")
+	}
+	for _, line := range lines {
+		if !block.Raw && asciidocCallout.MatchString(line) {
+			loc := asciidocCallout.FindStringIndex(line)
+			line = line[:loc[0]] + `\` + line[loc[0]:]
+		}
+		sb.WriteString(line + "\n")
+	}
+	if !block.Raw {
+		sb.WriteString(delimiter + "\n")
+	}
+	return sb.String(), nil
+}
+
+// rstBlock writes a code-block directive with the content indented below it,
+// followed by a blank line so that the directive ends before the next text
+func rstBlock(block codeBlock) (string, error) {
+	var sb strings.Builder
+	if block.Raw {
+		for _, line := range contentLines(block.Content) {
+			sb.WriteString(line + "\n")
+		}
+		return sb.String(), nil
+	}
+
+	language := block.Language.String()
+	if block.Language == Plain {
+		language = "text"
+	}
+	sb.WriteString(".. code-block:: " + language + "\n")
+	if block.caption != "" {
+		sb.WriteString("   :caption: " + block.caption + "\n")
+	}
+	if block.render.fenceID != "" {
+		sb.WriteString("   :name: " + block.render.fenceID + "\n")
+	}
+	if block.startFrom > 0 {
+		sb.WriteString(fmt.Sprintf("   :linenos:\n   :lineno-start: %d\n", block.startFrom))
+	}
+	if len(block.highlighted) > 0 {
+		sb.WriteString("   :emphasize-lines: " +
+			formatLineRanges(block.highlighted, ",", "-") + "\n")
+	}
+	sb.WriteString("\n")
+
+	for _, line := range contentLines(block.Content) {
+		if strings.TrimSpace(line) == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString("   " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+// lstlistingLanguages maps the languages of snippets to the names used by
+// the LaTeX listings package, which does not know every language
+var lstlistingLanguages = map[Filetype]string{
+	Python:   "Python",
+	Pycon:    "Python",
+	"c":      "C",
+	"cpp":    "C++",
+	"java":   "Java",
+	"csharp": "[Sharp]C",
+}
+
+// lstlistingBlock writes a lstlisting environment of the LaTeX listings
+// package
+func lstlistingBlock(block codeBlock) (string, error) {
+	if block.Raw {
+		return strings.Join(contentLines(block.Content), "\n") + "\n", nil
+	}
+	if len(block.highlighted) > 0 {
+		return "", fmt.Errorf("The %s output format can not highlight lines, use %s",
+			FormatLaTeX, FormatLaTeXMinted)
+	}
+	if strings.Contains(block.Content, `\end{lstlisting}`) {
+		return "", errors.New(`The snippet holds \end{lstlisting}, which would end the listing early`)
+	}
+
+	options := []string{}
+	if language, ok := lstlistingLanguages[block.Language]; ok {
+		options = append(options, "language="+language)
+	}
+	if block.caption != "" {
+		options = append(options, "caption={"+block.caption+"}")
+	}
+	if block.render.fenceID != "" {
+		options = append(options, "label={"+block.render.fenceID+"}")
+	}
+	if block.caption != "" && block.captionBelow {
+		options = append(options, "captionpos=b")
+	}
+	if block.startFrom > 0 {
+		options = append(options, "numbers=left", fmt.Sprintf("firstnumber=%d", block.startFrom))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`\begin{lstlisting}`)
+	if len(options) > 0 {
+		sb.WriteString("[" + strings.Join(options, ", ") + "]")
+	}
+	sb.WriteString("\n")
+	for _, line := range contentLines(block.Content) {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(`\end{lstlisting}` + "\n")
+	return sb.String(), nil
+}
+
+// mintedBlock writes a minted environment, inside of a listing float when
+// the block has a caption or a label
+func mintedBlock(block codeBlock) (string, error) {
+	if block.Raw {
+		return strings.Join(contentLines(block.Content), "\n") + "\n", nil
+	}
+	if strings.Contains(block.Content, `\end{minted}`) {
+		return "", errors.New(`The snippet holds \end{minted}, which would end the listing early`)
+	}
+
+	options := []string{}
+	if block.startFrom > 0 {
+		options = append(options, "linenos", fmt.Sprintf("firstnumber=%d", block.startFrom))
+	}
+	if len(block.highlighted) > 0 {
+		options = append(options,
+			"highlightlines={"+formatLineRanges(block.highlighted, ",", "-")+"}")
+	}
+	language := block.Language.String()
+	if block.Language == Plain {
+		language = "text"
+	}
+
+	float := block.caption != "" || block.render.fenceID != ""
+	var sb strings.Builder
+	caption := ""
+	if block.caption != "" {
+		caption = `\caption{` + block.caption + "}\n"
+	}
+	if block.render.fenceID != "" {
+		caption += `\label{` + block.render.fenceID + "}\n"
+	}
+
+	if float {
+		sb.WriteString(`\begin{listing}` + "\n")
+		if !block.captionBelow {
+			sb.WriteString(caption)
+		}
+	}
+	sb.WriteString(`\begin{minted}`)
+	if len(options) > 0 {
+		sb.WriteString("[" + strings.Join(options, ", ") + "]")
+	}
+	sb.WriteString("{" + language + "}\n")
+	for _, line := range contentLines(block.Content) {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(`\end{minted}` + "\n")
+	if float {
+		if block.captionBelow {
+			sb.WriteString(caption)
+		}
+		sb.WriteString(`\end{listing}` + "\n")
+	}
+	return sb.String(), nil
+}
+
+// orgEscaped matches the lines of a source block that Org would read as
+// markup, which are escaped with a leading comma
+var orgEscaped = regexp.MustCompile(`^(\s*)(,*(\*|#\+))`)
+
+// orgBlock writes a #+BEGIN_SRC block with its caption and name above it
+func orgBlock(block codeBlock) (string, error) {
+	var sb strings.Builder
+	if block.Raw {
+		for _, line := range contentLines(block.Content) {
+			sb.WriteString(line + "\n")
+		}
+		return sb.String(), nil
+	}
+	if len(block.highlighted) > 0 {
+		return "", fmt.Errorf("The %s output format can not highlight lines", FormatOrg)
+	}
+
+	if block.caption != "" {
+		sb.WriteString("#+CAPTION: " + block.caption + "\n")
+	}
+	if block.render.fenceID != "" {
+		sb.WriteString("#+NAME: " + block.render.fenceID + "\n")
+	}
+
+	language := block.Language.String()
+	if block.Language == Plain {
+		language = "text"
+	}
+	sb.WriteString("#+BEGIN_SRC " + language)
+	if block.startFrom > 0 {
+		sb.WriteString(fmt.Sprintf(" -n %d", block.startFrom))
+	}
+	sb.WriteString("\n")
+	for _, line := range contentLines(block.Content) {
+		sb.WriteString(orgEscaped.ReplaceAllString(line, "$1,$2") + "\n")
+	}
+	sb.WriteString("#+END_SRC\n")
+	return sb.String(), nil
+}
+
+// latexSpecial holds the replacements of the characters LaTeX treats as
+// markup
+var latexSpecial = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// escapeLaTeX escapes text placed into LaTeX markup
+func escapeLaTeX(s string) string {
+	return latexSpecial.Replace(s)
+}
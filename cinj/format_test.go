@@ -0,0 +1,116 @@
+package cinj
+
+import (
+	"testing"
+)
+
+func TestOutputFormats(t *testing.T) {
+	var text numberedText
+	text.add("def f():\n    return 1 # <1>\n", 10)
+	snippet := text.snippet(Python)
+
+	tests := []struct {
+		format   string
+		args     []string
+		expected string
+	}{
+		{FormatAsciiDoc, []string{"--fence-id=lst:f", "--start-from", "--highlight=2"},
+			"[[lst:f]]\n.Listing 1\n[source,python,linenums,start=10,highlight=\"2\"]\n----\n" +
+				"def f():\n    return 1 # \\<1>\n----\n"},
+		{FormatRST, []string{"--highlight=1-2"},
+			".. code-block:: python\n   :caption: Listing 1\n   :emphasize-lines: 1-2\n\n" +
+				"   def f():\n       return 1 # <1>\n\n"},
+		{FormatLaTeX, []string{"--fence-id=lst:f", "--start-from"},
+			"\\begin{lstlisting}[language=Python, caption={Listing 1}, label={lst:f}, " +
+				"numbers=left, firstnumber=10]\ndef f():\n    return 1 # <1>\n\\end{lstlisting}\n"},
+		{FormatLaTeXMinted, []string{"--highlight=2"},
+			"\\begin{listing}\n\\caption{Listing 1}\n\\begin{minted}[highlightlines={2}]{python}\n" +
+				"def f():\n    return 1 # <1>\n\\end{minted}\n\\end{listing}\n"},
+		{FormatOrg, []string{"--start-from"},
+			"#+CAPTION: Listing 1\n#+BEGIN_SRC python -n 10\ndef f():\n    return 1 # <1>\n#+END_SRC\n"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		block, err := r.codeBlock(snippet, "Listing 1")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := outputFormats[tt.format].writeBlock(block)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i, tt.expected, got)
+		}
+	}
+
+	r, _ := parseRenderArgs([]string{"--highlight=1"})
+	block, _ := r.codeBlock(snippet, "")
+	for _, format := range []string{FormatLaTeX, FormatOrg} {
+		if _, err := outputFormats[format].writeBlock(block); err == nil {
+			t.Fatalf("%s - expected an error for highlighted lines", format)
+		}
+	}
+}
+
+func TestOutputFormatEscaping(t *testing.T) {
+	content := "* heading\n#+TITLE: x\n----\n\\end{lstlisting}\n"
+	block, err := newRenderArgs().codeBlock(Snippet{Content: content, Language: Plain}, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "#+BEGIN_SRC text\n,* heading\n,#+TITLE: x\n----\n\\end{lstlisting}\n#+END_SRC\n"
+	if got, _ := orgBlock(block); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	expected = "[source]\n-----\n* heading\n#+TITLE: x\n----\n\\end{lstlisting}\n-----\n"
+	if got, _ := asciidocBlock(block); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	if _, err := lstlistingBlock(block); err == nil {
+		t.Fatal("expected an error for a snippet holding the end of the listing")
+	}
+
+	if got := escapeLaTeX(`my_file#1.py`); got != `my\_file\#1.py` {
+		t.Fatalf("wrong escaping, got %s", got)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		format   string
+		basename string
+	}{
+		{"docs/report.cinj", FormatMarkdown, "docs/report"},
+		{"docs/report.cinj.md", FormatMarkdown, "docs/report"},
+		{"docs/report.cinj.adoc", FormatAsciiDoc, "docs/report"},
+		{"report.cinj.rst", FormatRST, "report"},
+		{"report.cinj.tex", FormatLaTeX, "report"},
+		{"report.cinj.org", FormatOrg, "report"},
+	}
+
+	for i, tt := range tests {
+		format, basename, err := FormatForPath(tt.path)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if format != tt.format || basename != tt.basename {
+			t.Fatalf("tests[%d] - expected %s %s, got %s %s", i, tt.format,
+				tt.basename, format, basename)
+		}
+	}
+
+	for _, path := range []string{"report.md", "report.cinj.txt"} {
+		if _, _, err := FormatForPath(path); err == nil {
+			t.Fatalf("%s - expected an error", path)
+		}
+	}
+}
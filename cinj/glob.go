@@ -0,0 +1,170 @@
+package cinj
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Heading holds the fields an --each-heading format can use, for every file
+// of a command
+type Heading struct {
+	Path   string // file, relative to the file being worked on
+	Base   string // name of the file, such as handler.py
+	Dir    string // directory of the file, relative to the file being worked on
+	Stem   string // name of the file without its extension, such as handler
+	Ext    string // extension of the file, such as .py
+	Number int    // position of the file in the matches, counting from 1
+	Count  int    // number of files matched
+}
+
+// isGlob reports whether the path of a cinj command is a glob pattern such as
+// ./handlers/*.py. A path that exists as it is is not a pattern
+func isGlob(p string) bool {
+	if isURL(p) || !strings.ContainsAny(p, "*?[") {
+		return false
+	}
+	_, err := os.Stat(p)
+	return err != nil
+}
+
+// expandGlob returns the files matching a pattern, sorted. Besides the
+// syntax of filepath.Match, a ** path element matches any number of
+// directories
+func expandGlob(pattern string) ([]string, error) {
+	matches := []string{}
+	if !strings.Contains(pattern, "**") {
+		found, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern %s: %w", pattern, err)
+		}
+		for _, match := range found {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				matches = append(matches, match)
+			}
+		}
+		sort.Strings(matches)
+		return matches, nil
+	}
+
+	// walk the directory before the first element with a wildcard
+	elements := strings.Split(filepath.ToSlash(pattern), "/")
+	fixed := 0
+	for fixed < len(elements) && !strings.ContainsAny(elements[fixed], "*?[") {
+		fixed++
+	}
+	root := strings.Join(elements[:fixed], "/")
+	if fixed == 0 {
+		root = "."
+	} else if root == "" {
+		root = "/"
+	}
+
+	err := filepath.WalkDir(filepath.FromSlash(root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(root), p)
+		if err != nil {
+			return err
+		}
+		ok, err := matchElements(elements[fixed:], strings.Split(filepath.ToSlash(rel), "/"))
+		if ok {
+			matches = append(matches, p)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pattern %s: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchElements matches the elements of a path against the elements of a
+// pattern, where ** matches any number of elements
+func matchElements(pattern []string, elements []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(elements) == 0, nil
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elements); i++ {
+			if ok, err := matchElements(pattern[1:], elements[i:]); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	if len(elements) == 0 {
+		return false, nil
+	}
+	ok, err := path.Match(pattern[0], elements[0])
+	if !ok || err != nil {
+		return false, err
+	}
+	return matchElements(pattern[1:], elements[1:])
+}
+
+// each returns a command for every file matched by the glob of a command,
+// or the command itself when it has no glob
+func (cmd CinjCommand) each() []CinjCommand {
+	if cmd.Glob == "" {
+		return []CinjCommand{cmd}
+	}
+	commands := make([]CinjCommand, len(cmd.Filepaths))
+	for i, p := range cmd.Filepaths {
+		one := cmd
+		one.Filepath = p
+		one.Filepaths = []string{p}
+		one.Revisions = []string{cmd.revision(i)}
+		commands[i] = one
+	}
+	return commands
+}
+
+// parseHeadingFormat parses an --each-heading format, a text/template format
+// string using the fields of Heading
+func parseHeadingFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("heading").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid heading format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// heading returns the heading written above the snippet of the file of a
+// command, the number-th of `count` files
+func (c Cinj) heading(format string, cmd CinjCommand, number int, count int) (string, error) {
+	tmpl, err := parseHeadingFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	p := cmd.Filepath
+	if rel, err := filepath.Rel(filepath.Dir(c.Filepath), p); err == nil && filepath.IsLocal(rel) {
+		p = rel
+	}
+	p = filepath.ToSlash(p)
+	base := path.Base(p)
+	dir := path.Dir(p)
+
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, Heading{
+		Path:   p,
+		Base:   base,
+		Dir:    dir,
+		Stem:   strings.TrimSuffix(base, path.Ext(base)),
+		Ext:    path.Ext(base),
+		Number: number,
+		Count:  count,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Could not write heading: %w", err)
+	}
+	return sb.String(), nil
+}
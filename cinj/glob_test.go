@@ -0,0 +1,57 @@
+package cinj
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"handlers/b.py":        "def handle():\n    return 'b'\n",
+		"handlers/a.py":        "def handle():\n    return 'a'\n",
+		"handlers/nested/c.py": "def handle():\n    return 'c'\n",
+		"handlers/notes.txt":   "not python\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	src := filepath.Join(dir, "report.cinj")
+	c := Cinj{Filepath: src, Newname: filepath.Join(dir, "report.md")}
+	run := func(directive string) (string, error) {
+		if err := os.WriteFile(src, []byte(directive+"\n\nafter\n"), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := c.Run(); err != nil {
+			return "", err
+		}
+		got, err := os.ReadFile(c.Newname)
+		return string(got), err
+	}
+
+	got, err := run(`cinj{./handlers/*.py --function=handle --each-heading="### {{.Base}} ({{.Number}}/{{.Count}})"}`)
+	expected := "### a.py (1/2)\n\n```python\ndef handle():\n    return 'a'\n```\n\n" +
+		"### b.py (2/2)\n\n```python\ndef handle():\n    return 'b'\n```\nafter\n"
+	if err != nil || got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s (%v)", expected, got, err)
+	}
+
+	got, err = run(`cinj{./handlers/**/*.py --function=handle --caption-format="{{.Path}}"}`)
+	if err != nil || strings.Count(got, "```python") != 3 ||
+		!strings.Contains(got, "handlers/nested/c.py\n") || strings.Index(got, "a.py") > strings.Index(got, "c.py") {
+		t.Fatalf("expected three files in order with captions, got\n%s (%v)", got, err)
+	}
+
+	if _, err := run("cinj{./handlers/*.go}"); err == nil || !strings.Contains(err.Error(), "No files match ./handlers/*.go") {
+		t.Fatalf("expected an error for a glob matching nothing, got %v", err)
+	}
+}
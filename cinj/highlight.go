@@ -0,0 +1,131 @@
+package cinj
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output flavors, the renderers the Markdown output is written for. They
+// differ in how highlighted lines are marked on a code block
+const (
+	FlavorPandoc     = "pandoc"     // {.python hl_lines="3 7-9"}
+	FlavorMkDocs     = "mkdocs"     // python hl_lines="3 7-9"
+	FlavorVitePress  = "vitepress"  // python{3,7-9}
+	FlavorDocusaurus = "docusaurus" // python {3,7-9}
+)
+
+// checkFlavor returns an error for an unknown output flavor. An empty flavor
+// is pandoc
+func checkFlavor(flavor string) error {
+	switch flavor {
+	case "", FlavorPandoc, FlavorMkDocs, FlavorVitePress, FlavorDocusaurus:
+		return nil
+	}
+	return fmt.Errorf("Unknown flavor %s, expected %s, %s, %s or %s", flavor,
+		FlavorPandoc, FlavorMkDocs, FlavorVitePress, FlavorDocusaurus)
+}
+
+// lineInterval is a range of lines from first to last, counting from 1
+type lineInterval struct {
+	first int
+	last  int
+}
+
+// parseLineList parses a list of lines and ranges such as 3,7-9. The ranges
+// are kept as they are, to be checked against the length of a snippet before
+// their lines are used
+func parseLineList(s string) ([]lineInterval, error) {
+	intervals := []lineInterval{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("Invalid line range %s, expected lines such as 3,7-9",
+				part)
+		}
+		intervals = append(intervals, lineInterval{start, end})
+	}
+	return intervals, nil
+}
+
+// highlightedLines returns the lines of the snippet to highlight, counting
+// from 1 at the first line of the snippet, sorted and without duplicates
+func (r renderArgs) highlightedLines(snippet Snippet) ([]int, error) {
+	if r.highlight == "" && r.highlightMatch == "" {
+		return nil, nil
+	}
+
+	count := strings.Count(snippet.Content, "\n")
+	if !strings.HasSuffix(snippet.Content, "\n") {
+		count++
+	}
+
+	seen := map[int]bool{}
+	intervals, err := parseLineList(r.highlight)
+	if err != nil {
+		return nil, err
+	}
+	for _, interval := range intervals {
+		if interval.last > count {
+			return nil, fmt.Errorf("Can not highlight line %d, the snippet has %d lines",
+				interval.last, count)
+		}
+		for line := interval.first; line <= interval.last; line++ {
+			seen[line] = true
+		}
+	}
+
+	if r.highlightMatch != "" {
+		re, err := regexp.Compile(r.highlightMatch)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid highlight match %s: %w", r.highlightMatch, err)
+		}
+		matched := false
+		for i, line := range strings.Split(strings.TrimSuffix(snippet.Content, "\n"), "\n") {
+			if re.MatchString(line) {
+				seen[i+1] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("No line of the snippet matches %s", r.highlightMatch)
+		}
+	}
+
+	highlighted := []int{}
+	for line := range seen {
+		highlighted = append(highlighted, line)
+	}
+	sort.Ints(highlighted)
+	return highlighted, nil
+}
+
+// formatLineRanges joins sorted lines into ranges such as 3,7-9, using `sep`
+// between the ranges and `dash` between the first and last line of a range
+func formatLineRanges(lines []int, sep string, dash string) string {
+	ranges := []string{}
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d%s%d", lines[i], dash, lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, sep)
+}
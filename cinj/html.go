@@ -0,0 +1,205 @@
+package cinj
+
+import (
+	_ "embed"
+	"html"
+	"strconv"
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+	"github.com/TheDavo/cinj/lexers/clike"
+	"github.com/TheDavo/cinj/lexers/python"
+)
+
+// htmlStyle is the theme bundled with every HTML file, holding the colors of
+// the token classes written by highlightCode
+//
+//go:embed html.css
+var htmlStyle string
+
+// Token classes of highlighted code
+const (
+	tokenKeyword = "k"
+	tokenString  = "s"
+	tokenComment = "c"
+	tokenNumber  = "n"
+)
+
+// codeTokens returns the tokens of code in a language, using the lexer of the
+// language. Languages without a lexer have no tokens
+func codeTokens(code string, language Filetype) []lex.Token {
+	if language == Python {
+		return python.Tokenize(code)
+	}
+	if cLanguage, ok := clike.ForName(string(language)); ok {
+		lexer := clike.NewLexer(code, cLanguage)
+		lexer.Lex()
+		tokens := lexer.Tokens()
+		for i, tok := range tokens {
+			if tok.Type == clike.IDENT && cLanguage.IsKeyword(tok.Literal) {
+				tokens[i].Type = python.KEYWORD
+			}
+		}
+		return tokens
+	}
+	return nil
+}
+
+// tokenClass returns the highlighting class of a token type, empty for
+// tokens that are not highlighted. The python and clike lexers use the same
+// names for the token types
+func tokenClass(tt lex.TokenType) string {
+	switch tt {
+	case python.KEYWORD:
+		return tokenKeyword
+	case python.STRING:
+		return tokenString
+	case python.COMMENT:
+		return tokenComment
+	case python.NUMBER:
+		return tokenNumber
+	}
+	return ""
+}
+
+// highlightCode returns every line of code as escaped HTML, with the
+// highlighted tokens wrapped in spans. A token running over several lines,
+// such as a block comment, is split into a span on each line
+func highlightCode(code string, language Filetype) []string {
+	code = strings.TrimSuffix(code, "\n")
+	classes := make([]string, len(code))
+	for _, tok := range codeTokens(code, language) {
+		class := tokenClass(tok.Type)
+		for i := tok.StartPosition; i < tok.EndPosition && i < len(code); i++ {
+			classes[i] = class
+		}
+	}
+
+	lines := []string{}
+	start := 0
+	for _, line := range strings.Split(code, "\n") {
+		var sb strings.Builder
+		for i := 0; i < len(line); {
+			j := i
+			for j < len(line) && classes[start+j] == classes[start+i] {
+				j++
+			}
+			text := html.EscapeString(strings.TrimSuffix(line[i:j], "\r"))
+			if class := classes[start+i]; class != "" {
+				text = `<span class="` + class + `">` + text + "</span>"
+			}
+			sb.WriteString(text)
+			i = j
+		}
+		lines = append(lines, sb.String())
+		start += len(line) + 1
+	}
+	return lines
+}
+
+// codeHTML returns a highlighted pre element for code. Lines listed in
+// `highlighted`, counting from 1, are marked, and `numbers` holds the line
+// number shown in front of every line, or is nil for code without numbers
+func codeHTML(code string, language Filetype, classes []string,
+	highlighted []int, numbers []int) string {
+	var sb strings.Builder
+
+	class := strings.Join(append([]string{"code"}, classes...), " ")
+	sb.WriteString(`<pre class="` + html.EscapeString(class) + `"`)
+	if language != Plain {
+		sb.WriteString(` data-language="` + html.EscapeString(language.String()) + `"`)
+	}
+	sb.WriteString("><code>")
+
+	for i, line := range highlightCode(code, language) {
+		lineClass := "line"
+		for _, hl := range highlighted {
+			if hl == i+1 {
+				lineClass += " hl"
+			}
+		}
+		sb.WriteString(`<span class="` + lineClass + `">`)
+		if numbers != nil {
+			number := ""
+			if i < len(numbers) && numbers[i] > 0 {
+				number = strconv.Itoa(numbers[i])
+			}
+			sb.WriteString(`<span class="ln">` + number + "</span>")
+		}
+		sb.WriteString(line + "</span>\n")
+	}
+
+	sb.WriteString("</code></pre>\n")
+	return sb.String()
+}
+
+// htmlBlock writes a highlighted pre element, inside of a figure when the
+// block has a caption or an identifier. Raw snippets are left as Markdown,
+// to be rendered with the rest of the document
+func htmlBlock(block codeBlock) (string, error) {
+	if block.Raw {
+		return strings.Join(contentLines(block.Content), "\n") + "\n", nil
+	}
+
+	numbers := block.lineNumbers
+	if numbers == nil && block.startFrom > 0 {
+		count := len(contentLines(block.Content))
+		lines, err := block.sourceLines()
+		if err == nil && len(lines) == count && lines[0] == block.startFrom {
+			numbers = lines
+		} else {
+			for i := 0; i < count; i++ {
+				numbers = append(numbers, block.startFrom+i)
+			}
+		}
+	}
+	pre := codeHTML(block.Content, block.Language, block.render.fenceClass,
+		block.highlighted, numbers)
+
+	if block.caption == "" && block.render.fenceID == "" {
+		return pre, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<figure class="listing"`)
+	if block.render.fenceID != "" {
+		sb.WriteString(` id="` + html.EscapeString(block.render.fenceID) + `"`)
+	}
+	sb.WriteString(">\n")
+	caption := ""
+	if block.caption != "" {
+		caption = "<figcaption>" + block.caption + "</figcaption>\n"
+	}
+	if !block.captionBelow {
+		sb.WriteString(caption)
+	}
+	sb.WriteString(pre)
+	if block.captionBelow {
+		sb.WriteString(caption)
+	}
+	sb.WriteString("</figure>\n")
+	return sb.String(), nil
+}
+
+// htmlDocument renders the Markdown written by Cinj into a standalone HTML
+// file with the bundled theme. The title is the first level one heading, or
+// `name` when there is none
+func htmlDocument(markdown string, name string) string {
+	r := newMarkdownRenderer()
+	body := r.render(markdown)
+
+	title := r.title
+	if title == "" {
+		title = name
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	sb.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	sb.WriteString("<style>\n" + htmlStyle + "</style>\n")
+	sb.WriteString("</head>\n<body>\n<main>\n")
+	sb.WriteString(body)
+	sb.WriteString("</main>\n</body>\n</html>\n")
+	return sb.String()
+}
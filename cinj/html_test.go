@@ -0,0 +1,115 @@
+package cinj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		language Filetype
+		code     string
+		expected []string
+	}{
+		{Python, "def f(x):\n    return 'a<b' # done\n", []string{
+			`<span class="k">def</span> f(x):`,
+			`    <span class="k">return</span> <span class="s">&#39;a&lt;b&#39;</span> <span class="c"># done</span>`,
+		}},
+		{"go", "/* a\nb */ x := 42", []string{
+			`<span class="c">/* a</span>`,
+			`<span class="c">b */</span> x := <span class="n">42</span>`,
+		}},
+		{Plain, "if x & y", []string{"if x &amp; y"}},
+	}
+
+	for i, tt := range tests {
+		got := highlightCode(tt.code, tt.language)
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i,
+				strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestHTMLBlock(t *testing.T) {
+	r, err := parseRenderArgs([]string{"--fence-id=lst:f", "--start-from", "--highlight=2"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var text numberedText
+	text.add("x = 1\ny = 2\n", 7)
+	block, err := r.codeBlock(text.snippet(Python), "Listing 1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `<figure class="listing" id="lst:f">
+<figcaption>Listing 1</figcaption>
+<pre class="code" data-language="python"><code><span class="line"><span class="ln">7</span>x = <span class="n">1</span></span>
+<span class="line hl"><span class="ln">8</span>y = <span class="n">2</span></span>
+</code></pre>
+</figure>
+`
+	if got, _ := htmlBlock(block); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestHTMLLineNumbers(t *testing.T) {
+	r, err := parseRenderArgs([]string{"--line-numbers"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r.gutter = true
+	var text numberedText
+	text.add("def f():\n    \"\"\"\n    Returns 25.\n    \"\"\"\n", 24)
+	text.add("    ...\n", 0)
+	text.add("    return 25\n", 30)
+	block, err := r.codeBlock(text.snippet(Python), "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `<pre class="code" data-language="python"><code><span class="line"><span class="ln">24</span><span class="k">def</span> f():</span>
+<span class="line"><span class="ln">25</span>    <span class="s">&#34;&#34;&#34;</span></span>
+<span class="line"><span class="ln">26</span><span class="s">    Returns 25.</span></span>
+<span class="line"><span class="ln">27</span><span class="s">    &#34;&#34;&#34;</span></span>
+<span class="line"><span class="ln"></span>    ...</span>
+<span class="line"><span class="ln">30</span>    <span class="k">return</span> <span class="n">25</span></span>
+</code></pre>
+`
+	if got, _ := htmlBlock(block); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	tests := []struct {
+		markdown string
+		expected string
+	}{
+		{"# Title\n\nSome *em*, **strong** and `a<b`.\n",
+			"<h1 id=\"title\">Title</h1>\n<p>Some <em>em</em>, <strong>strong</strong> and <code>a&lt;b</code>.</p>\n"},
+		{"- one\n- two\n\n1. a\n", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>a</li>\n</ol>\n"},
+		{"- one\n\n- two\n", "<ul>\n<li><p>one</p></li>\n<li><p>two</p></li>\n</ul>\n"},
+		{"> [link](https://a.b) and <https://c.d>\n",
+			"<blockquote>\n<p><a href=\"https://a.b\">link</a> and <a href=\"https://c.d\">https://c.d</a></p>\n</blockquote>\n"},
+		{"| a | b |\n| --- | :-: |\n| 1 | 2 |\n",
+			"<table>\n<thead>\n<tr><th>a</th><th style=\"text-align: center\">b</th></tr>\n</thead>\n" +
+				"<tbody>\n<tr><td>1</td><td style=\"text-align: center\">2</td></tr>\n</tbody>\n</table>\n"},
+		{"<pre>\n\na *b*\n</pre>\n\n---\n", "<pre>\n\na *b*\n</pre>\n<hr />\n"},
+		{"~~~\n<x>\n~~~\n", "<pre class=\"code\"><code><span class=\"line\">&lt;x&gt;</span>\n</code></pre>\n"},
+	}
+
+	for i, tt := range tests {
+		if got := newMarkdownRenderer().render(tt.markdown); got != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i, tt.expected, got)
+		}
+	}
+
+	r := newMarkdownRenderer()
+	got := r.render("Intro\n\n# Setup\n\n## Setup\n")
+	if r.title != "Setup" || !strings.Contains(got, `<h2 id="setup-1">`) {
+		t.Fatalf("wrong title %q or heading identifiers\n%s", r.title, got)
+	}
+}
@@ -0,0 +1,147 @@
+package cinj
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// numberedText builds the content of a snippet out of pieces of text,
+// keeping track of the source line of every line of the content
+type numberedText struct {
+	sb    strings.Builder
+	lines []int
+	open  bool // the last line does not end with a newline yet
+}
+
+// add appends text whose first line is the source line `line`. A line of 0
+// marks text that is not from the source, such as a separator. Text that
+// continues an unfinished line keeps the number of that line
+func (t *numberedText) add(text string, line int) {
+	lines := make([]int, strings.Count(text, "\n")+1)
+	for i := range lines {
+		if line > 0 {
+			lines[i] = line + i
+		}
+	}
+	t.addNumbered(text, lines)
+}
+
+// addNumbered appends text whose lines come from the source lines `lines`
+func (t *numberedText) addNumbered(text string, lines []int) {
+	for i, part := range strings.SplitAfter(text, "\n") {
+		if part == "" {
+			continue
+		}
+		if !t.open {
+			number := 0
+			if i < len(lines) {
+				number = lines[i]
+			}
+			t.lines = append(t.lines, number)
+		}
+		t.sb.WriteString(part)
+		t.open = !strings.HasSuffix(part, "\n")
+	}
+}
+
+// snippet returns the built content with its line numbers
+func (t *numberedText) snippet(language Filetype) Snippet {
+	snippet := Snippet{
+		Content:     t.sb.String(),
+		Language:    language,
+		LineNumbers: t.lines,
+	}
+	snippet.StartLine, snippet.EndLine = lineSpan(snippet.Content, t.lines)
+	return snippet
+}
+
+// lineSpan returns the first and last source lines of content that are not
+// blank
+func lineSpan(content string, lines []int) (int, int) {
+	start, end := 0, 0
+	for i, line := range strings.Split(content, "\n") {
+		if i >= len(lines) || lines[i] == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if start == 0 {
+			start = lines[i]
+		}
+		end = lines[i]
+	}
+	return start, end
+}
+
+// sourceLines returns the source line of every line of the snippet content,
+// or an error when the snippet does not know where it came from
+func (s Snippet) sourceLines() ([]int, error) {
+	if s.LineNumbers != nil {
+		return s.LineNumbers, nil
+	}
+	if s.StartLine == 0 {
+		return nil, errors.New("The source line numbers of the snippet are not known, " +
+			"notebook cells, doctest examples, diffs and trees have none")
+	}
+
+	count := strings.Count(s.Content, "\n")
+	if !strings.HasSuffix(s.Content, "\n") {
+		count++
+	}
+	lines := make([]int, count)
+	for i := range lines {
+		lines[i] = s.StartLine + i
+	}
+	return lines, nil
+}
+
+// numberLines prefixes every line of the snippet content with its source
+// line number. Lines that are not from the source get a blank prefix
+func numberLines(s Snippet) (Snippet, error) {
+	lines, err := s.sourceLines()
+	if err != nil {
+		return s, err
+	}
+
+	width := len(fmt.Sprint(s.EndLine))
+	for _, line := range lines {
+		width = max(width, len(fmt.Sprint(line)))
+	}
+
+	var sb strings.Builder
+	for i, text := range strings.SplitAfter(s.Content, "\n") {
+		if text == "" {
+			continue
+		}
+		prefix := strings.Repeat(" ", width)
+		if i < len(lines) && lines[i] > 0 {
+			prefix = fmt.Sprintf("%*d", width, lines[i])
+		}
+		sb.WriteString(prefix + "  " + text)
+	}
+
+	s.Content = sb.String()
+	return s, nil
+}
+
+// lineRange formats the source lines of a snippet for captions, such as
+// 40–72, or 40 for a single line
+func (s Snippet) lineRange() string {
+	if s.StartLine == 0 {
+		return ""
+	}
+	if s.EndLine <= s.StartLine {
+		return fmt.Sprint(s.StartLine)
+	}
+	return fmt.Sprintf("%d–%d", s.StartLine, s.EndLine)
+}
+
+// wholeFile returns the whole source as a snippet
+func wholeFile(src Source, language Filetype) Snippet {
+	content := string(src.Content)
+	return Snippet{
+		Content:   content,
+		Language:  language,
+		StartLine: 1,
+		EndLine:   max(1, strings.Count(strings.TrimRight(content, " \t\r\n"), "\n")+1),
+	}
+}
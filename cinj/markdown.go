@@ -0,0 +1,613 @@
+package cinj
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// markdownRenderer renders Markdown to HTML for the html output format. It
+// knows the syntax reports are written with: headings, paragraphs, lists,
+// block quotes, fenced and indented code, pipe tables, thematic breaks and
+// HTML blocks, and inline code, emphasis, links, images and autolinks. It
+// is not a full CommonMark implementation
+type markdownRenderer struct {
+	ids   map[string]int // heading identifiers used so far
+	title string         // text of the first level one heading
+}
+
+func newMarkdownRenderer() *markdownRenderer {
+	return &markdownRenderer{ids: map[string]int{}}
+}
+
+var (
+	mdFence       = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*(.*)$")
+	mdHeading     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdBreak       = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdQuote       = regexp.MustCompile(`^ {0,3}> ?`)
+	mdListItem    = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	mdSetext      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdTableSep    = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdHTMLBlock   = regexp.MustCompile(`^ {0,3}<(/?[A-Za-z][A-Za-z0-9-]*|!--)`)
+	mdLanguage    = regexp.MustCompile(`^\{?\.?([A-Za-z0-9_+#-]+)`)
+	mdInlineHTML  = regexp.MustCompile(`^</?[A-Za-z][A-Za-z0-9-]*(\s[^<>]*)?/?>`)
+	mdEntity      = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdAutolink    = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\s]*)>`)
+	mdLinkTarget  = regexp.MustCompile(`^\(\s*<?([^\s()<>]*)>?(?:\s+"([^"]*)")?\s*\)`)
+	mdNonSlugChar = regexp.MustCompile(`[^\p{L}\p{N}_ -]`)
+)
+
+// rawHTMLTags are the tags of HTML blocks that may hold blank lines, their
+// block only ends at the closing tag
+var rawHTMLTags = []string{"pre", "figure", "script", "style", "textarea"}
+
+// render returns the HTML of a Markdown document
+func (r *markdownRenderer) render(markdown string) string {
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(markdown, "\n"), "\n")
+	var sb strings.Builder
+	r.blocks(&sb, lines, false)
+	return sb.String()
+}
+
+// blocks renders the block level syntax of lines. Paragraphs of tight lists
+// are written without p elements
+func (r *markdownRenderer) blocks(sb *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFence.MatchString(line):
+			i = r.fencedCode(sb, lines, i)
+
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			r.heading(sb, len(m[1]), m[2])
+			i++
+
+		case mdBreak.MatchString(line):
+			sb.WriteString("<hr />\n")
+			i++
+
+		case mdHTMLBlock.MatchString(line):
+			i = r.htmlBlock(sb, lines, i)
+
+		case mdQuote.MatchString(line):
+			quoted := []string{}
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.ReplaceAllString(lines[i], ""))
+			}
+			sb.WriteString("<blockquote>\n")
+			r.blocks(sb, quoted, false)
+			sb.WriteString("</blockquote>\n")
+
+		case mdListItem.MatchString(line):
+			i = r.list(sb, lines, i)
+
+		case strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"):
+			code := []string{}
+			for ; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == "" {
+					code = append(code, "")
+					continue
+				}
+				if !strings.HasPrefix(lines[i], "    ") && !strings.HasPrefix(lines[i], "\t") {
+					break
+				}
+				code = append(code, strings.TrimPrefix(
+					strings.TrimPrefix(lines[i], "\t"), "    "))
+			}
+			for len(code) > 0 && code[len(code)-1] == "" {
+				code = code[:len(code)-1]
+			}
+			sb.WriteString(codeHTML(strings.Join(code, "\n"), Plain, nil, nil, nil))
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && mdTableSep.MatchString(lines[i+1]):
+			i = r.table(sb, lines, i)
+
+		default:
+			i = r.paragraph(sb, lines, i, tight)
+		}
+	}
+}
+
+// startsBlock reports whether a line starts a block that interrupts a
+// paragraph
+func startsBlock(line string) bool {
+	if mdFence.MatchString(line) || mdHeading.MatchString(line) ||
+		mdBreak.MatchString(line) || mdQuote.MatchString(line) ||
+		mdHTMLBlock.MatchString(line) {
+		return true
+	}
+	m := mdListItem.FindStringSubmatch(line)
+	return m != nil && strings.TrimSpace(line[len(m[0]):]) != "" &&
+		(strings.ContainsAny(m[2], "-*+") || strings.TrimRight(m[2], ".)") == "1")
+}
+
+// fencedCode renders the fenced code block starting at lines[i] and returns
+// the index of the line after it
+func (r *markdownRenderer) fencedCode(sb *strings.Builder, lines []string, i int) int {
+	m := mdFence.FindStringSubmatch(lines[i])
+	fence := m[1]
+	var language Filetype = Plain
+	if lm := mdLanguage.FindStringSubmatch(m[2]); lm != nil {
+		language = Filetype(strings.ToLower(lm[1]))
+	}
+
+	code := []string{}
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	sb.WriteString(codeHTML(strings.Join(code, "\n"), language, nil, nil, nil))
+	return i
+}
+
+// heading writes a heading with an identifier made from its text
+func (r *markdownRenderer) heading(sb *strings.Builder, level int, text string) {
+	content := r.inline(strings.TrimSpace(text))
+	plain := html.UnescapeString(stripTags(content))
+	if level == 1 && r.title == "" {
+		r.title = plain
+	}
+
+	id := strings.ReplaceAll(mdNonSlugChar.ReplaceAllString(
+		strings.ToLower(strings.TrimSpace(plain)), ""), " ", "-")
+	if id == "" {
+		id = "section"
+	}
+	if n := r.ids[id]; n > 0 {
+		r.ids[id]++
+		id = fmt.Sprintf("%s-%d", id, n)
+	} else {
+		r.ids[id] = 1
+	}
+
+	fmt.Fprintf(sb, "<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(id),
+		content, level)
+}
+
+// htmlBlock copies the HTML block starting at lines[i] and returns the index
+// of the line after it. Blocks of the rawHTMLTags run to their closing tag,
+// other blocks to the next blank line
+func (r *markdownRenderer) htmlBlock(sb *strings.Builder, lines []string, i int) int {
+	tag := strings.ToLower(mdHTMLBlock.FindStringSubmatch(lines[i])[1])
+	closing := ""
+	for _, raw := range rawHTMLTags {
+		if tag == raw {
+			closing = "</" + raw + ">"
+		}
+	}
+
+	for ; i < len(lines); i++ {
+		if closing == "" && strings.TrimSpace(lines[i]) == "" {
+			break
+		}
+		sb.WriteString(lines[i] + "\n")
+		if closing != "" && strings.Contains(strings.ToLower(lines[i]), closing) {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// list renders the list starting at lines[i] and returns the index of the
+// line after it
+func (r *markdownRenderer) list(sb *strings.Builder, lines []string, i int) int {
+	first := mdListItem.FindStringSubmatch(lines[i])
+	ordered := !strings.ContainsAny(first[2], "-*+")
+	delimiter := first[2][len(first[2])-1:]
+
+	items := [][]string{}
+	tight := true
+	blank := false
+	indent := 0 // indentation of the content of the current item
+	for i < len(lines) {
+		line := lines[i]
+		m := mdListItem.FindStringSubmatch(line)
+		if m != nil && strings.HasSuffix(m[2], delimiter) &&
+			ordered == !strings.ContainsAny(m[2], "-*+") &&
+			(len(items) == 0 || len(m[1]) < indent) {
+			if blank {
+				tight = false
+			}
+			indent = len(m[0])
+			if m[3] == "" || len(m[3]) > 4 {
+				// an empty first line, or indented code right after the
+				// marker
+				indent = len(m[1]) + len(m[2]) + 1
+			}
+			items = append(items, []string{strings.TrimPrefix(line, m[0])})
+			if len(m[0]) < len(line) && len(m[3]) > 4 {
+				items[len(items)-1][0] = line[indent:]
+			}
+			blank = false
+			i++
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			items[len(items)-1] = append(items[len(items)-1], "")
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(line, strings.Repeat(" ", indent)) || strings.HasPrefix(line, "\t") {
+			if blank && !mdListItem.MatchString(strings.TrimSpace(line)) {
+				// a blank line between the blocks of an item makes the list
+				// loose, unless the block is a nested list
+				tight = false
+			}
+			if strings.HasPrefix(line, "\t") {
+				line = line[1:]
+			} else {
+				line = line[indent:]
+			}
+			items[len(items)-1] = append(items[len(items)-1], line)
+			blank = false
+			i++
+			continue
+		}
+
+		if !blank && !startsBlock(line) {
+			// lazy continuation of the paragraph of the last item
+			items[len(items)-1] = append(items[len(items)-1], line)
+			i++
+			continue
+		}
+		break
+	}
+
+	tag := "ul"
+	start := ""
+	if ordered {
+		tag = "ol"
+		number := strings.TrimLeft(strings.TrimRight(first[2], ".)"), "0")
+		if number != "1" && number != "" {
+			start = ` start="` + number + `"`
+		} else if number == "" {
+			start = ` start="0"`
+		}
+	}
+
+	sb.WriteString("<" + tag + start + ">\n")
+	for _, item := range items {
+		var inner strings.Builder
+		r.blocks(&inner, item, tight)
+		sb.WriteString("<li>" + strings.TrimSuffix(inner.String(), "\n") + "</li>\n")
+	}
+	sb.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// table renders the pipe table starting at lines[i] and returns the index of
+// the line after it
+func (r *markdownRenderer) table(sb *strings.Builder, lines []string, i int) int {
+	header := tableCells(lines[i])
+	aligns := []string{}
+	for _, cell := range tableCells(lines[i+1]) {
+		cell = strings.TrimSpace(cell)
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(cell, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	row := func(cells []string, tag string) {
+		sb.WriteString("<tr>")
+		for j := range aligns {
+			text := ""
+			if j < len(cells) {
+				text = r.inline(strings.TrimSpace(cells[j]))
+			}
+			if aligns[j] != "" {
+				fmt.Fprintf(sb, `<%s style="text-align: %s">%s</%s>`, tag, aligns[j], text, tag)
+			} else {
+				fmt.Fprintf(sb, "<%s>%s</%s>", tag, text, tag)
+			}
+		}
+		sb.WriteString("</tr>\n")
+	}
+
+	sb.WriteString("<table>\n<thead>\n")
+	row(header, "th")
+	sb.WriteString("</thead>\n")
+
+	i += 2
+	if i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines[i]) {
+		sb.WriteString("<tbody>\n")
+		for ; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" || startsBlock(lines[i]) {
+				break
+			}
+			row(tableCells(lines[i]), "td")
+		}
+		sb.WriteString("</tbody>\n")
+	}
+	sb.WriteString("</table>\n")
+	return i
+}
+
+// tableCells splits a table row into its cells. Pipes inside of code spans
+// or escaped with a backslash do not split cells
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	cells := []string{}
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteString(`\|`)
+			i++
+		case line[i] == '`':
+			inCode = !inCode
+			cell.WriteByte('`')
+		case line[i] == '|' && !inCode:
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+// paragraph renders the paragraph, or setext heading, starting at lines[i]
+// and returns the index of the line after it
+func (r *markdownRenderer) paragraph(sb *strings.Builder, lines []string, i int, tight bool) int {
+	text := []string{strings.TrimSpace(lines[i])}
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if m := mdSetext.FindStringSubmatch(line); m != nil {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			r.heading(sb, level, strings.Join(text, "\n"))
+			return i + 1
+		}
+		if strings.TrimSpace(line) == "" || startsBlock(line) {
+			break
+		}
+		text = append(text, strings.TrimLeft(line, " \t"))
+	}
+
+	content := r.inline(strings.Join(text, "\n"))
+	if tight {
+		sb.WriteString(content + "\n")
+	} else {
+		sb.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+// inline renders the inline syntax of text
+func (r *markdownRenderer) inline(text string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		ch := text[i]
+
+		switch {
+		case ch == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			sb.WriteString("<br />\n")
+			i += 2
+			continue
+		case ch == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			sb.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case ch == '`':
+			run := len(rest) - len(strings.TrimLeft(rest, "`"))
+			fence := rest[:run]
+			end := closingRun(rest[run:], fence)
+			if end < 0 {
+				sb.WriteString(fence)
+				i += run
+				continue
+			}
+			code := strings.ReplaceAll(rest[run:run+end], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' &&
+				strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			i += run + end + run
+			continue
+
+		case ch == '!' && strings.HasPrefix(rest, "!["):
+			if label, url, title, n, ok := linkAt(rest[1:]); ok {
+				fmt.Fprintf(&sb, `<img src="%s" alt="%s"%s />`, html.EscapeString(url),
+					html.EscapeString(stripTags(r.inline(label))), titleAttr(title))
+				i += 1 + n
+				continue
+			}
+
+		case ch == '[':
+			if label, url, title, n, ok := linkAt(rest); ok {
+				fmt.Fprintf(&sb, `<a href="%s"%s>%s</a>`, html.EscapeString(url),
+					titleAttr(title), r.inline(label))
+				i += n
+				continue
+			}
+
+		case ch == '<':
+			if m := mdAutolink.FindStringSubmatch(rest); m != nil {
+				fmt.Fprintf(&sb, `<a href="%s">%s</a>`, html.EscapeString(m[1]),
+					html.EscapeString(m[1]))
+				i += len(m[0])
+				continue
+			}
+			if m := mdInlineHTML.FindString(rest); m != "" {
+				sb.WriteString(m)
+				i += len(m)
+				continue
+			}
+
+		case ch == '&':
+			if m := mdEntity.FindString(rest); m != "" {
+				sb.WriteString(m)
+				i += len(m)
+				continue
+			}
+
+		case ch == '*' || ch == '_':
+			if html, n, ok := r.emphasis(text, i); ok {
+				sb.WriteString(html)
+				i += n
+				continue
+			}
+
+		case ch == '\n':
+			if strings.HasSuffix(sb.String(), "  ") {
+				trimmed := strings.TrimRight(sb.String(), " ")
+				sb.Reset()
+				sb.WriteString(trimmed + "<br />\n")
+			} else {
+				sb.WriteString("\n")
+			}
+			i++
+			continue
+		}
+
+		sb.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+
+	return sb.String()
+}
+
+// emphasis renders the emphasis or strong emphasis opened by the delimiter
+// run at text[i], returning the HTML and the length of the text it used
+func (r *markdownRenderer) emphasis(text string, i int) (string, int, bool) {
+	ch := text[i : i+1]
+	run := ch
+	if strings.HasPrefix(text[i:], ch+ch) {
+		run = ch + ch
+	}
+	after := i + len(run)
+	if after >= len(text) || text[after] == ' ' || text[after] == '\n' {
+		return "", 0, false
+	}
+	if ch == "_" && i > 0 && isWordChar(text[i-1]) {
+		return "", 0, false
+	}
+
+	for j := after + 1; j+len(run) <= len(text); j++ {
+		if text[j] == '`' {
+			// skip over code spans, their content is not emphasis
+			run := len(text[j:]) - len(strings.TrimLeft(text[j:], "`"))
+			if end := closingRun(text[j+run:], text[j:j+run]); end >= 0 {
+				j += run + end + run - 1
+			}
+			continue
+		}
+		if len(run) == 1 && strings.HasPrefix(text[j:], ch+ch) {
+			// strong emphasis inside of the emphasis, skip over it
+			if end := strings.Index(text[j+2:], ch+ch); end >= 0 {
+				j += 2 + end + 1
+				continue
+			}
+		}
+		if !strings.HasPrefix(text[j:], run) || text[j-1] == ' ' || text[j-1] == '\n' {
+			continue
+		}
+		end := j + len(run)
+		if ch == "_" && end < len(text) && isWordChar(text[end]) {
+			continue
+		}
+		tag := "em"
+		if len(run) == 2 {
+			tag = "strong"
+		}
+		return "<" + tag + ">" + r.inline(text[after:j]) + "</" + tag + ">", end - i, true
+	}
+	return "", 0, false
+}
+
+// closingRun returns the index in s of a backtick run equal to fence, or -1
+func closingRun(s string, fence string) int {
+	for j := 0; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		run := len(s[j:]) - len(strings.TrimLeft(s[j:], "`"))
+		if run == len(fence) {
+			return j
+		}
+		j += run
+	}
+	return -1
+}
+
+// linkAt parses a link such as [label](url "title") at the start of s,
+// returning its parts and its length
+func linkAt(s string) (label string, url string, title string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				m := mdLinkTarget.FindStringSubmatch(s[i+1:])
+				if m == nil {
+					return "", "", "", 0, false
+				}
+				return s[1:i], m[1], m[2], i + 1 + len(m[0]), true
+			}
+		}
+	}
+	return "", "", "", 0, false
+}
+
+func titleAttr(title string) string {
+	if title == "" {
+		return ""
+	}
+	return ` title="` + html.EscapeString(title) + `"`
+}
+
+var mdTag = regexp.MustCompile(`<[^<>]*>`)
+
+// stripTags removes the HTML tags of rendered inline text
+func stripTags(s string) string {
+	return mdTag.ReplaceAllString(s, "")
+}
+
+func isASCIIPunct(ch byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", ch) >= 0
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch >= 0x80 || (ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,240 @@
+package cinj
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type notebookArgs struct {
+	cell    int
+	cells   string
+	tag     string
+	outputs bool
+}
+
+func newNotebookArgs() *notebookArgs {
+	return &notebookArgs{
+		cell:    0,
+		cells:   "",
+		tag:     "",
+		outputs: false,
+	}
+}
+
+// jupyterNotebook holds the parts of the Jupyter notebook format, nbformat 4,
+// that are needed to write cells into a Markdown file
+type jupyterNotebook struct {
+	Cells    []jupyterCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type jupyterCell struct {
+	CellType string          `json:"cell_type"`
+	Source   notebookText    `json:"source"`
+	Outputs  []jupyterOutput `json:"outputs"`
+	Metadata struct {
+		Tags []string `json:"tags"`
+	} `json:"metadata"`
+}
+
+type jupyterOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	Ename      string                  `json:"ename"`
+	Evalue     string                  `json:"evalue"`
+}
+
+// notebookText is text stored in a notebook, either as a single string or
+// as a list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*t = notebookText(text)
+	return nil
+}
+
+// language returns the language of the notebook kernel, used for the code
+// blocks of the code cells
+func (nb jupyterNotebook) language() Filetype {
+	if nb.Metadata.Kernelspec.Language != "" {
+		return Filetype(nb.Metadata.Kernelspec.Language)
+	}
+	if nb.Metadata.LanguageInfo.Name != "" {
+		return Filetype(nb.Metadata.LanguageInfo.Name)
+	}
+	return Python
+}
+
+// text returns the stored text outputs of a cell. Rich outputs such as
+// images are skipped
+func (output jupyterOutput) text() string {
+	switch output.OutputType {
+	case "stream":
+		return string(output.Text)
+	case "execute_result", "display_data":
+		return string(output.Data["text/plain"])
+	case "error":
+		return output.Ename + ": " + output.Evalue
+	}
+	return ""
+}
+
+// notebookExtractor grabs cells out of Jupyter notebooks. Code cells are
+// written in code blocks with the kernel language and Markdown cells are
+// written as they are
+type notebookExtractor struct{}
+
+func (notebookExtractor) FileType() Filetype {
+	return Notebook
+}
+
+func (notebookExtractor) Flags() []string {
+	return flagNames(newNotebookFlagSet(newNotebookArgs()))
+}
+
+// newNotebookFlagSet returns the flag set used to parse the cinj command
+// arguments for notebooks into `args`
+func newNotebookFlagSet(args *notebookArgs) *flag.FlagSet {
+	nbFlag := flag.NewFlagSet("nbFlag", flag.ContinueOnError)
+	nbFlag.SetOutput(io.Discard)
+	nbFlag.IntVar(&args.cell, "cell", 0, "Grab a single cell, starting from 1")
+	nbFlag.StringVar(&args.cells, "cells", "",
+		"Grab a range of cells, for example 2-5")
+	nbFlag.StringVar(&args.tag, "tag", "", "Grab the cells with this tag")
+	nbFlag.BoolVar(&args.outputs, "outputs", false,
+		"Add the stored text outputs after each code cell")
+
+	return nbFlag
+}
+
+// Extract parses the cinj command arguments and returns the cells of the
+// notebook they ask for
+func (notebookExtractor) Extract(src Source, args []string) (Snippet, error) {
+	nbArgs := newNotebookArgs()
+
+	err := newNotebookFlagSet(nbArgs).Parse(args)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	var nb jupyterNotebook
+	if err := json.Unmarshal(src.Content, &nb); err != nil {
+		return Snippet{}, fmt.Errorf("Could not read notebook %s: %w",
+			src.Path, err)
+	}
+
+	cells, err := nbArgs.selectCells(nb.Cells)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	language := nb.language()
+	snippet := Snippet{Language: language}
+	for _, cell := range cells {
+		source := string(cell.Source)
+		switch cell.CellType {
+		case "code":
+			snippet.Parts = append(snippet.Parts,
+				Snippet{Content: source, Language: language})
+			if !nbArgs.outputs {
+				continue
+			}
+			for _, output := range cell.Outputs {
+				if text := output.text(); text != "" {
+					snippet.Parts = append(snippet.Parts,
+						Snippet{Content: text, Language: Plain})
+				}
+			}
+		default:
+			snippet.Parts = append(snippet.Parts, Snippet{Content: source, Raw: true})
+		}
+	}
+
+	return snippet, nil
+}
+
+// selectCells returns the cells chosen with the cell, cells and tag
+// arguments, or every cell when none of them are given
+func (args notebookArgs) selectCells(cells []jupyterCell) ([]jupyterCell, error) {
+	if args.cell != 0 {
+		if args.cell < 1 || args.cell > len(cells) {
+			return nil, fmt.Errorf("Cell %d is out of range, the notebook has %d cells",
+				args.cell, len(cells))
+		}
+		return cells[args.cell-1 : args.cell], nil
+	}
+
+	if args.cells != "" {
+		first, last, err := parseRange(args.cells, len(cells))
+		if err != nil {
+			return nil, err
+		}
+		return cells[first-1 : last], nil
+	}
+
+	if args.tag != "" {
+		tagged := []jupyterCell{}
+		for _, cell := range cells {
+			for _, tag := range cell.Metadata.Tags {
+				if tag == args.tag {
+					tagged = append(tagged, cell)
+					break
+				}
+			}
+		}
+		if len(tagged) == 0 {
+			return nil, fmt.Errorf("No cells found with the tag %s", args.tag)
+		}
+		return tagged, nil
+	}
+
+	return cells, nil
+}
+
+// parseRange parses a 1-indexed range such as "2-5", or "3-" to go until
+// `count`. A single number is a range of one
+func parseRange(s string, count int) (int, int, error) {
+	firstStr, lastStr, isRange := strings.Cut(s, "-")
+	first, err := strconv.Atoi(firstStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse range %s", s)
+	}
+
+	last := first
+	if isRange {
+		last = count
+		if lastStr != "" {
+			last, err = strconv.Atoi(lastStr)
+			if err != nil {
+				return 0, 0, fmt.Errorf("Could not parse range %s", s)
+			}
+		}
+	}
+
+	if first < 1 || last < first || last > count {
+		return 0, 0, fmt.Errorf("Range %s is out of bounds, there are %d", s, count)
+	}
+	return first, last, nil
+}
@@ -0,0 +1,126 @@
+package cinj
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNotebookExtract(t *testing.T) {
+	content, err := os.ReadFile("../examples/example.ipynb")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	src := Source{Path: "example.ipynb", Content: content}
+
+	tests := []struct {
+		args     []string
+		expected []Snippet
+	}{
+		{[]string{"--cell=2"}, []Snippet{
+			{Content: "data = [1, 2, 3]\nprint(\"rows:\", len(data))", Language: Python},
+		}},
+		{[]string{"--cells=1-2"}, []Snippet{
+			{Content: "# Analysis\nLoad the data first.", Raw: true},
+			{Content: "data = [1, 2, 3]\nprint(\"rows:\", len(data))", Language: Python},
+		}},
+		{[]string{"--cells=3-"}, []Snippet{
+			{Content: "sum(data)", Language: Python},
+		}},
+		{[]string{"--tag=plot-setup", "--outputs"}, []Snippet{
+			{Content: "sum(data)", Language: Python},
+			{Content: "6", Language: Plain},
+		}},
+		{[]string{"--cell=2", "--outputs"}, []Snippet{
+			{Content: "data = [1, 2, 3]\nprint(\"rows:\", len(data))", Language: Python},
+			{Content: "rows: 3\n", Language: Plain},
+		}},
+		{[]string{}, []Snippet{
+			{Content: "# Analysis\nLoad the data first.", Raw: true},
+			{Content: "data = [1, 2, 3]\nprint(\"rows:\", len(data))", Language: Python},
+			{Content: "sum(data)", Language: Python},
+		}},
+	}
+
+	for i, tt := range tests {
+		got, err := notebookExtractor{}.Extract(src, tt.args)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if len(got.Parts) != len(tt.expected) {
+			t.Fatalf("tests[%d] - expected %d parts, got %d", i, len(tt.expected), len(got.Parts))
+		}
+		for j, part := range got.Parts {
+			want := tt.expected[j]
+			if part.Content != want.Content || part.Language != want.Language || part.Raw != want.Raw {
+				t.Fatalf("tests[%d] - part %d: expected %+v, got %+v", i, j, want, part)
+			}
+		}
+	}
+
+	errorTests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--cell=4"}, "Cell 4 is out of range, the notebook has 3 cells"},
+		{[]string{"--cell=-1"}, "Cell -1 is out of range"},
+		{[]string{"--cells=2-5"}, "Range 2-5 is out of bounds"},
+		{[]string{"--cells=3-2"}, "Range 3-2 is out of bounds"},
+		{[]string{"--cells=a-2"}, "Could not parse range a-2"},
+		{[]string{"--tag=missing"}, "No cells found with the tag missing"},
+	}
+	for i, tt := range errorTests {
+		_, err := notebookExtractor{}.Extract(src, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("errorTests[%d] - expected %q, got %v", i, tt.expected, err)
+		}
+	}
+}
+
+func TestNotebookLanguage(t *testing.T) {
+	tests := []struct {
+		metadata string
+		expected Filetype
+	}{
+		{`{"kernelspec": {"language": "julia"}, "language_info": {"name": "python"}}`, "julia"},
+		{`{"language_info": {"name": "r"}}`, "r"},
+		{`{}`, Python},
+	}
+
+	for i, tt := range tests {
+		var nb jupyterNotebook
+		if err := json.Unmarshal([]byte(`{"cells": [], "metadata": `+tt.metadata+`}`), &nb); err != nil {
+			t.Fatal(err.Error())
+		}
+		if got := nb.language(); got != tt.expected {
+			t.Fatalf("tests[%d] - expected %q, got %q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		input string
+		first int
+		last  int
+	}{
+		{"2-5", 2, 5},
+		{"3-", 3, 10},
+		{"4", 4, 4},
+		{"10-10", 10, 10},
+	}
+
+	for i, tt := range tests {
+		first, last, err := parseRange(tt.input, 10)
+		if err != nil || first != tt.first || last != tt.last {
+			t.Fatalf("tests[%d] - expected %d-%d, got %d-%d (%v)", i, tt.first, tt.last, first, last, err)
+		}
+	}
+
+	for _, input := range []string{"0-2", "5-11", "x", "2-y", ""} {
+		if _, _, err := parseRange(input, 10); err == nil {
+			t.Fatalf("expected an error for range %q", input)
+		}
+	}
+}
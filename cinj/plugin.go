@@ -0,0 +1,174 @@
+package cinj
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultPluginTimeout is how long a plugin can run when its config does
+// not set a timeout
+const defaultPluginTimeout = 10 * time.Second
+
+// PluginConfig describes an external program used as an extractor
+type PluginConfig struct {
+	// Command is the program and its arguments. A relative program path is
+	// resolved from the directory of the config file
+	Command []string `json:"command"`
+	// Language is used for the code blocks when the plugin does not return
+	// a language
+	Language string `json:"language"`
+	// Timeout is a duration such as "5s", defaulting to 10 seconds
+	Timeout string `json:"timeout"`
+}
+
+func (p PluginConfig) timeout() (time.Duration, error) {
+	if p.Timeout == "" {
+		return defaultPluginTimeout, nil
+	}
+	timeout, err := time.ParseDuration(p.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %s", p.Timeout)
+	}
+	return timeout, nil
+}
+
+// pluginRequest is written as JSON to the standard input of a plugin
+type pluginRequest struct {
+	Path    string            `json:"path"`
+	Content string            `json:"content"`
+	Args    map[string]string `json:"args"`
+	RawArgs []string          `json:"raw_args"`
+}
+
+// pluginResponse is read as JSON from the standard output of a plugin
+type pluginResponse struct {
+	Content   string `json:"content"`
+	Language  string `json:"language"`
+	Name      string `json:"name"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Error     string `json:"error"`
+}
+
+// pluginExtractor runs an external program to extract a snippet. The program
+// gets the file path, content and command arguments as JSON on its standard
+// input and writes the snippet as JSON to its standard output
+type pluginExtractor struct {
+	config PluginConfig
+	dir    string
+}
+
+func (p pluginExtractor) FileType() Filetype {
+	return Filetype(p.config.Language)
+}
+
+// Flags returns nil as the arguments are checked by the plugin itself
+func (p pluginExtractor) Flags() []string {
+	return nil
+}
+
+func (p pluginExtractor) Extract(src Source, args []string) (Snippet, error) {
+	name := p.config.Command[0]
+	if strings.ContainsRune(name, '/') && !filepath.IsAbs(name) {
+		name = filepath.Join(p.dir, name)
+	}
+
+	request, err := json.Marshal(pluginRequest{
+		Path:    src.Path,
+		Content: string(src.Content),
+		Args:    parseArgMap(args),
+		RawArgs: args,
+	})
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	timeout, err := p.config.timeout()
+	if err != nil {
+		return Snippet{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, p.config.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Snippet{}, fmt.Errorf("Plugin %s timed out after %s%s",
+			p.config.Command[0], timeout, stderrSuffix(stderr))
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return Snippet{}, fmt.Errorf("Plugin %s exited with status %d%s",
+			p.config.Command[0], exitErr.ExitCode(), stderrSuffix(stderr))
+	}
+	if err != nil {
+		return Snippet{}, fmt.Errorf("Could not run plugin %s: %w",
+			p.config.Command[0], err)
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return Snippet{}, fmt.Errorf("Plugin %s wrote an invalid response: %w%s",
+			p.config.Command[0], err, stderrSuffix(stderr))
+	}
+	if response.Error != "" {
+		return Snippet{}, fmt.Errorf("Plugin %s: %s", p.config.Command[0],
+			response.Error)
+	}
+
+	language := Filetype(response.Language)
+	if language == "" {
+		language = p.FileType()
+	}
+
+	return Snippet{
+		Content:   response.Content,
+		Language:  language,
+		Name:      response.Name,
+		StartLine: response.StartLine,
+		EndLine:   response.EndLine,
+	}, nil
+}
+
+// stderrSuffix formats what a plugin wrote to its standard error for the
+// end of an error message
+func stderrSuffix(stderr bytes.Buffer) string {
+	text := strings.TrimSpace(stderr.String())
+	if text == "" {
+		return ""
+	}
+	return ": " + text
+}
+
+// parseArgMap turns cinj command arguments into a map of argument names to
+// values. Arguments without a value, such as --all, are set to "true"
+func parseArgMap(args []string) map[string]string {
+	parsed := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !hasValue {
+			value = "true"
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				value = args[i+1]
+				i++
+			}
+		}
+		parsed[name] = value
+	}
+	return parsed
+}
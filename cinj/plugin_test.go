@@ -0,0 +1,102 @@
+package cinj
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPluginHelperProcess is run as the external plugin by the plugin tests,
+// it does nothing when run as a normal test
+func TestPluginHelperProcess(t *testing.T) {
+	mode := os.Getenv("CINJ_PLUGIN_HELPER")
+	if mode == "" {
+		return
+	}
+
+	var request pluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	switch mode {
+	case "ok":
+		json.NewEncoder(os.Stdout).Encode(pluginResponse{
+			Content:   request.Args["block"] + ": " + strings.TrimSpace(request.Content),
+			Language:  "dsl",
+			StartLine: 3,
+			EndLine:   4,
+		})
+	case "fail":
+		fmt.Fprintln(os.Stderr, "block not found")
+		os.Exit(3)
+	case "slow":
+		time.Sleep(5 * time.Second)
+	}
+	os.Exit(0)
+}
+
+func pluginForTest(t *testing.T, mode string, timeout string) pluginExtractor {
+	t.Setenv("CINJ_PLUGIN_HELPER", mode)
+	return pluginExtractor{config: PluginConfig{
+		Command: []string{os.Args[0], "-test.run=^TestPluginHelperProcess$"},
+		Timeout: timeout,
+	}}
+}
+
+func TestPluginExtract(t *testing.T) {
+	plugin := pluginForTest(t, "ok", "")
+	src := Source{Path: "rules.dsl", Content: []byte("rule a\n")}
+
+	snippet, err := plugin.Extract(src, []string{"--block=rules", "--all"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := Snippet{Content: "rules: rule a", Language: "dsl", StartLine: 3, EndLine: 4}
+	if snippet.Content != expected.Content || snippet.Language != expected.Language ||
+		snippet.StartLine != expected.StartLine || snippet.EndLine != expected.EndLine {
+		t.Fatalf("Expected %+v, got %+v", expected, snippet)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	src := Source{Path: "rules.dsl", Content: []byte("rule a\n")}
+
+	tests := []struct {
+		mode     string
+		timeout  string
+		expected string
+	}{
+		{"fail", "", "exited with status 3: block not found"},
+		{"slow", "100ms", "timed out after 100ms"},
+	}
+
+	for i, test := range tests {
+		plugin := pluginForTest(t, test.mode, test.timeout)
+		_, err := plugin.Extract(src, []string{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Fatalf("tests[%d]: expected an error containing %q, got %v",
+				i, test.expected, err)
+		}
+	}
+}
+
+func TestPluginConfigTimeout(t *testing.T) {
+	for _, timeout := range []string{"0s", "-5s", "soon"} {
+		dir := t.TempDir()
+		config := `{"plugins": {".dsl": {"command": ["dsl-plugin"], "timeout": "` + timeout + `"}}}`
+		if err := os.WriteFile(filepath.Join(dir, ConfigName), []byte(config), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+		_, err := LoadConfig(filepath.Join(dir, ConfigName))
+		if err == nil || !strings.Contains(err.Error(), "invalid timeout "+timeout) {
+			t.Fatalf("expected an invalid timeout error for %s, got %v", timeout, err)
+		}
+	}
+}
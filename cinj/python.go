@@ -3,91 +3,419 @@ package cinj
 import (
 	"errors"
 	"flag"
-	"log"
-	"os"
+	"fmt"
+	"io"
 	"strings"
 
 	pylex "github.com/TheDavo/cinj/lexers/python"
 )
 
 type pythonArgs struct {
-	class    string
-	function string
+	class      string
+	function   string
+	variable   string
+	attribute  string
+	imports    bool
+	occurrence int
+	all        bool
+	decorator  string
+	context    string
+	separator  string
+	main       bool
+	doctest    string
 }
 
+// Context styles for a definition extracted from inside of a class or
+// function
+const (
+	contextSeparator = ""          // class line and a separator, the default
+	contextNone      = "none"      // only the definition
+	contextClass     = "class"     // class line and an ellipsis
+	contextFullPath  = "full-path" // every enclosing class and function
+)
+
 func newPythonArgs() *pythonArgs {
 	return &pythonArgs{
-		class:    "",
-		function: "",
+		class:      "",
+		function:   "",
+		variable:   "",
+		attribute:  "",
+		imports:    false,
+		occurrence: 0,
+		all:        false,
+		decorator:  "",
+		context:    contextSeparator,
+		separator:  "",
+		main:       false,
+		doctest:    "",
 	}
 }
 
-// python uses the flag package to parse the cinj command into appropriate
-// variables to later use them in the parsePython function
-func (cmd CinjCommand) python() (string, error) {
-	var class string
-	var function string
-	var content string
+// pythonExtractor grabs classes, functions and other blocks out of Python
+// files using the Python lexer
+type pythonExtractor struct{}
 
-	pyArgs := newPythonArgs()
+func (pythonExtractor) FileType() Filetype {
+	return Python
+}
 
-	pyFlag := flag.NewFlagSet("pyFlag", flag.PanicOnError)
-	pyFlag.StringVar(&class, "class", "", "Grab entire content of a class")
-	pyFlag.StringVar(&function, "function", "", "Grab contents of a function")
+func (pythonExtractor) Flags() []string {
+	return flagNames(newPythonFlagSet(newPythonArgs()))
+}
 
-	err := pyFlag.Parse(cmd.Args)
+// Extract parses the cinj command arguments and returns the parts of the
+// Python source they ask for
+func (pythonExtractor) Extract(src Source, args []string) (Snippet, error) {
+	pyArgs, err := parsePythonArgs(args)
 	if err != nil {
-		log.Fatal(err)
+		return Snippet{}, err
 	}
 
-	if class != "" {
-		pyArgs.class = strings.Split(class, " ")[0]
+	snippet, err := parsePython(src, *pyArgs)
+	if pyArgs.doctest != "" {
+		snippet.Language = Pycon
 	}
 
-	if function != "" {
-		pyArgs.function = strings.Split(function, " ")[0]
+	return snippet, err
+}
+
+// newPythonFlagSet returns the flag set used to parse the cinj command
+// arguments for Python files into `args`
+func newPythonFlagSet(args *pythonArgs) *flag.FlagSet {
+	pyFlag := flag.NewFlagSet("pyFlag", flag.ContinueOnError)
+	pyFlag.SetOutput(io.Discard)
+	pyFlag.StringVar(&args.class, "class", "", "Grab entire content of a class")
+	pyFlag.StringVar(&args.function, "function", "", "Grab contents of a function")
+	pyFlag.StringVar(&args.variable, "variable", "", "Grab a module level assignment")
+	pyFlag.StringVar(&args.attribute, "attribute", "",
+		"Grab a class attribute, used together with --class")
+	pyFlag.BoolVar(&args.imports, "imports", false, "Grab the import block")
+	pyFlag.IntVar(&args.occurrence, "occurrence", 0,
+		"Grab the Nth definition when a name is defined more than once")
+	pyFlag.BoolVar(&args.all, "all", false,
+		"Grab every definition when a name is defined more than once")
+	pyFlag.StringVar(&args.decorator, "decorator", "",
+		"Only grab definitions with this decorator, for example setter")
+	pyFlag.StringVar(&args.context, "context", contextSeparator,
+		"Enclosing blocks shown above a definition: none, class or full-path")
+	pyFlag.StringVar(&args.separator, "separator", "",
+		"Line shown between the enclosing blocks and the definition")
+	pyFlag.BoolVar(&args.main, "main", false,
+		"Grab the if __name__ == \"__main__\" block")
+	pyFlag.StringVar(&args.doctest, "doctest", "",
+		"Grab the interactive examples from the docstring of a function")
+
+	return pyFlag
+}
+
+// parsePythonArgs uses the flag package to parse the cinj command into
+// appropriate variables to later use them in the parsePython function
+func parsePythonArgs(args []string) (*pythonArgs, error) {
+	pyArgs := newPythonArgs()
+
+	err := newPythonFlagSet(pyArgs).Parse(args)
+	if err != nil {
+		return nil, err
 	}
 
-	content, err = cmd.parsePython(*pyArgs)
+	if pyArgs.occurrence < 0 {
+		return nil, errors.New("The occurrence argument must be 1 or greater")
+	}
+
+	if pyArgs.occurrence > 0 && pyArgs.all {
+		return nil, errors.New("The occurrence and all arguments can not be used together")
+	}
 
-	return content, err
+	switch pyArgs.context {
+	case contextSeparator, contextNone, contextClass, contextFullPath:
+	default:
+		return nil, fmt.Errorf("Unknown context %s, expected none, class or full-path",
+			pyArgs.context)
+	}
+
+	return pyArgs, nil
 }
 
 // parsePython parses a python file for the appropriate content based on the
-// arguments passed in the python() function call
-func (cmd CinjCommand) parsePython(args pythonArgs) (string, error) {
-	if args.class == "" && args.function == "" {
-		content, err := cmd.returnAll()
-		return content, err
+// arguments parsed by parsePythonArgs
+func parsePython(src Source, args pythonArgs) (Snippet, error) {
+	if args.class == "" && args.function == "" && args.variable == "" &&
+		args.attribute == "" && !args.imports && !args.main &&
+		args.doctest == "" {
+		return wholeFile(src, Python), nil
 	}
 
-	content, err := os.ReadFile(cmd.Filepath)
-	if err != nil {
-		log.Fatal(err)
-		return "", err
-	}
-	pl := pylex.NewLexer(string(content), 4)
+	pl := pylex.NewLexer(string(src.Content), 4)
 	pl.Lex()
 
+	if args.imports {
+		imports, err := pl.FindImports()
+		if err != nil {
+			return Snippet{}, err
+		}
+		var text numberedText
+		for _, statement := range imports {
+			text.add(statement.Text, statement.Line)
+		}
+		return text.snippet(Python), nil
+	}
+
+	if args.main {
+		block, err := pl.FindMainBlock()
+		if err != nil {
+			return Snippet{}, err
+		}
+		return statementSnippet(block), nil
+	}
+
+	if args.doctest != "" {
+		content, err := getDoctest(pl, args)
+		return Snippet{Content: content, Language: Pycon,
+			Name: qualifiedName(args.class, args.doctest)}, err
+	}
+
+	if args.variable != "" {
+		variable, err := pl.FindVariable(args.variable)
+		if err != nil {
+			return Snippet{}, err
+		}
+		snippet := statementSnippet(variable)
+		snippet.Name = args.variable
+		return snippet, nil
+	}
+
+	// Looking for an attribute of a class
+	if args.attribute != "" {
+		if args.class == "" {
+			return Snippet{}, errors.New("The attribute argument requires a class argument")
+		}
+		class, attribute, err := pl.FindAttribute(args.attribute, args.class)
+		if err != nil {
+			return Snippet{}, err
+		}
+		var text numberedText
+		text.add(class.Header, class.Line)
+		text.add("#----\n", 0)
+		text.add(attribute.Text, attribute.Line)
+		snippet := text.snippet(Python)
+		snippet.Name = qualifiedName(args.class, args.attribute)
+		return snippet, nil
+	}
+
 	// Looking only for a class
 	if args.class != "" && args.function == "" {
-		class, err := pl.GetClass(args.class)
+		classes, err := pl.FindClasses(args.class)
 		if err != nil {
-			log.Fatal(err.Error())
+			return Snippet{}, err
 		}
 
-		return class, nil
-
+		return selectDefinitions(pl, classes, args, "")
 	}
 	// Looking for function
 	if args.function != "" {
-		functionText, err := pl.GetFunction(args.function, args.class)
+		functions, err := pl.FindFunctions(args.function, args.class)
+		if err != nil {
+			return Snippet{}, err
+		}
+
+		return selectDefinitions(pl, functions, args, args.class)
+	}
+
+	return Snippet{}, errors.New("Could not parse python file for wanted parameters")
+}
+
+// getDoctest returns the interactive examples from the docstring of the
+// function named in the doctest argument
+func getDoctest(pl *pylex.PythonLexer, args pythonArgs) (string, error) {
+	functions, err := pl.FindFunctions(args.doctest, args.class)
+	if err != nil {
+		return "", err
+	}
+
+	for _, function := range functions {
+		docstring, err := pl.Docstring(function)
 		if err != nil {
-			log.Fatal(err.Error())
+			continue
 		}
+		if examples := pylex.Doctest(docstring); examples != "" {
+			return examples, nil
+		}
+	}
 
-		return functionText, nil
+	return "", fmt.Errorf("Could not find doctest examples in the docstring of %s",
+		args.doctest)
+}
+
+// selectDefinitions narrows down the definitions found for a name using the
+// decorator, occurrence and all arguments. A name that is defined more than
+// once without any of these arguments is an error listing every candidate
+func selectDefinitions(
+	pl *pylex.PythonLexer,
+	defs []pylex.Definition,
+	args pythonArgs,
+	className string,
+) (Snippet, error) {
+	name := defs[0].Name
+
+	if args.decorator != "" {
+		decorated := []pylex.Definition{}
+		for _, def := range defs {
+			if def.HasDecorator(args.decorator) {
+				decorated = append(decorated, def)
+			}
+		}
+		if len(decorated) == 0 {
+			return Snippet{}, fmt.Errorf("No definition of %s has the decorator %s, found at %s",
+				name, args.decorator, definitionLocations(defs))
+		}
+		defs = decorated
 	}
 
-	return "", errors.New("Could not parse python file for wanted parameters")
+	if args.all {
+		var all numberedText
+		for i, def := range defs {
+			part := withContext(pl, def, args, className)
+			text := strings.TrimLeft(part.Content, "\r\n")
+			lines := part.LineNumbers[strings.Count(part.Content[:len(part.Content)-len(text)], "\n"):]
+			if i > 0 {
+				all.add("\n", 0)
+			}
+			all.addNumbered(strings.TrimRight(text, " \t\r\n")+"\n", lines)
+		}
+		snippet := all.snippet(Python)
+		snippet.Name = qualifiedName(defs[0].Class(), name)
+		return snippet, nil
+	}
+
+	if args.occurrence > 0 {
+		if args.occurrence > len(defs) {
+			return Snippet{}, fmt.Errorf("Asked for occurrence %d of %s but found %d, at %s",
+				args.occurrence, name, len(defs), definitionLocations(defs))
+		}
+		return withContext(pl, defs[args.occurrence-1], args, className), nil
+	}
+
+	if len(defs) > 1 {
+		return Snippet{}, fmt.Errorf(
+			"%s is ambiguous, found %d definitions at %s; use --occurrence, --all or --decorator",
+			name, len(defs), definitionLocations(defs))
+	}
+
+	return withContext(pl, defs[0], args, className), nil
+}
+
+// definitionLocations lists where each definition is for error messages
+func definitionLocations(defs []pylex.Definition) string {
+	locations := make([]string, len(defs))
+	for i, def := range defs {
+		locations[i] = def.Location()
+	}
+	return strings.Join(locations, ", ")
+}
+
+// withContext returns the text of a definition with the blocks enclosing it
+// shown above, in the style asked for by the context argument
+func withContext(
+	pl *pylex.PythonLexer,
+	def pylex.Definition,
+	args pythonArgs,
+	className string,
+) Snippet {
+	var text numberedText
+	var headers []pylex.Scope
+	separator := args.separator
+
+	switch args.context {
+	case contextSeparator:
+		if separator == "" {
+			separated := pl.ClassSeparated(def, className)
+			if scopes := classScope(def, className); className != "" &&
+				len(scopes) > 0 && separated != def.Text {
+				text.add(strings.TrimSuffix(separated, "#----"+def.Text), scopes[0].Line)
+				text.add("#----", 0)
+			}
+			text.add(def.Text, def.StartLine)
+			return definitionSnippet(&text, def)
+		}
+		headers = classScope(def, className)
+	case contextNone:
+		addTrimmed(&text, def)
+		return definitionSnippet(&text, def)
+	case contextClass:
+		headers = classScope(def, className)
+		if separator == "" {
+			separator = "..."
+		}
+	case contextFullPath:
+		headers = def.Scopes
+	}
+
+	if len(headers) == 0 {
+		text.add(def.Text, def.StartLine)
+		return definitionSnippet(&text, def)
+	}
+
+	for _, header := range headers {
+		text.add(header.Header, header.Line)
+	}
+	if separator != "" {
+		text.add(def.Indent+separator+"\n", 0)
+	}
+	addTrimmed(&text, def)
+
+	return definitionSnippet(&text, def)
+}
+
+// definitionSnippet returns the snippet of a definition built in `text`.
+// The start and end lines are those of the definition, without the
+// enclosing blocks shown above it
+func definitionSnippet(text *numberedText, def pylex.Definition) Snippet {
+	snippet := text.snippet(Python)
+	trimmed := strings.TrimLeft(def.Text, "\r\n")
+	snippet.StartLine = def.StartLine + strings.Count(def.Text[:len(def.Text)-len(trimmed)], "\n")
+	snippet.EndLine = def.EndLine
+	if def.Kind == pylex.CLASS {
+		snippet.Name = def.Name
+	} else {
+		snippet.Name = qualifiedName(def.Class(), def.Name)
+	}
+	return snippet
+}
+
+// qualifiedName joins a class name and the name of something inside of it,
+// such as Client.fetch
+func qualifiedName(className string, name string) string {
+	if className == "" {
+		return name
+	}
+	return className + "." + name
+}
+
+// addTrimmed adds the text of a definition without its leading blank lines
+// and the blank or indentation-only lines after its block
+func addTrimmed(text *numberedText, def pylex.Definition) {
+	trimmed := strings.TrimLeft(def.Text, "\r\n")
+	leading := strings.Count(def.Text[:len(def.Text)-len(trimmed)], "\n")
+	text.add(strings.TrimRight(trimmed, " \t\r\n")+"\n", def.StartLine+leading)
+}
+
+// statementSnippet returns a statement found by the lexer as a snippet
+func statementSnippet(statement pylex.Statement) Snippet {
+	var text numberedText
+	text.add(statement.Text, statement.Line)
+	return text.snippet(Python)
+}
+
+// classScope returns the enclosing class `className` of a definition, or its
+// closest enclosing class when no class name was asked for
+func classScope(def pylex.Definition, className string) []pylex.Scope {
+	if className == "" {
+		className = def.Class()
+	}
+	for _, scope := range def.Scopes {
+		if scope.Kind == pylex.CLASS && scope.Name == className {
+			return []pylex.Scope{scope}
+		}
+	}
+	return []pylex.Scope{}
 }
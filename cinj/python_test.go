@@ -0,0 +1,103 @@
+package cinj
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPythonContext(t *testing.T) {
+	source := `class Outer:
+    x = 1
+
+    class Inner:
+        def method(self):
+            return 1
+
+        def other(self):
+            pass
+
+
+def f():
+    pass
+`
+	src := Source{Path: "a.py", Content: []byte(source)}
+
+	tests := []struct {
+		args      []string
+		expected  string
+		startLine int
+	}{
+		{[]string{"--class=Inner", "--function=method", "--context=none"},
+			"        def method(self):\n            return 1\n", 5},
+		{[]string{"--class=Inner", "--function=method", "--context=class"},
+			"    class Inner:\n        ...\n        def method(self):\n            return 1\n", 5},
+		{[]string{"--class=Inner", "--function=method", "--context=class", "--separator=# snip"},
+			"    class Inner:\n        # snip\n        def method(self):\n            return 1\n", 5},
+		{[]string{"--class=Inner", "--function=method", "--context=full-path"},
+			"class Outer:\n    class Inner:\n        def method(self):\n            return 1\n", 5},
+		{[]string{"--function=other", "--context=full-path", "--separator=..."},
+			"class Outer:\n    class Inner:\n        ...\n        def other(self):\n            pass\n", 8},
+		{[]string{"--function=f", "--context=full-path"},
+			"\ndef f():\n    pass\n", 12},
+	}
+
+	for i, tt := range tests {
+		got, err := pythonExtractor{}.Extract(src, tt.args)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%q\ngot\n%q", i, tt.expected, got.Content)
+		}
+		if got.StartLine != tt.startLine {
+			t.Fatalf("tests[%d] - expected start line %d, got %d", i, tt.startLine, got.StartLine)
+		}
+	}
+
+	if _, err := (pythonExtractor{}).Extract(src, []string{"--function=f", "--context=module"}); err == nil {
+		t.Fatal("expected an error for an unknown context")
+	}
+}
+
+func TestPythonStatementLines(t *testing.T) {
+	source := `USAGE = """
+import os
+
+if __name__ == "__main__":
+    run()
+"""
+import os
+
+
+class Config:
+    retries = 3
+
+if __name__ == "__main__":
+    run()
+`
+	src := Source{Path: "a.py", Content: []byte(source)}
+
+	tests := []struct {
+		args     []string
+		expected []int
+	}{
+		{[]string{"--imports"}, []int{7}},
+		{[]string{"--main"}, []int{13, 14}},
+		{[]string{"--class=Config", "--attribute=retries"}, []int{10, 0, 11}},
+		{[]string{"--variable=USAGE"}, []int{1, 2, 3, 4, 5, 6}},
+	}
+
+	for i, tt := range tests {
+		got, err := pythonExtractor{}.Extract(src, tt.args)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		lines, err := got.sourceLines()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if fmt.Sprint(lines) != fmt.Sprint(tt.expected) {
+			t.Fatalf("tests[%d] - expected lines %v, got %v", i, tt.expected, lines)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cinj
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TheDavo/cinj/lexers/clike"
+)
+
+type queryArgs struct {
+	query      string
+	occurrence int
+}
+
+func newQueryArgs() *queryArgs {
+	return &queryArgs{
+		query:      "",
+		occurrence: 0,
+	}
+}
+
+// queryExtractor grabs declarations out of brace-delimited languages, such as
+// Go, JavaScript and Rust, with a query on the tree found by the clike parser
+type queryExtractor struct {
+	language *clike.Language
+}
+
+func (e queryExtractor) FileType() Filetype {
+	return Filetype(e.language.Name)
+}
+
+func (queryExtractor) Flags() []string {
+	return flagNames(newQueryFlagSet(newQueryArgs()))
+}
+
+// newQueryFlagSet returns the flag set used to parse the cinj command
+// arguments for query based files into `args`
+func newQueryFlagSet(args *queryArgs) *flag.FlagSet {
+	qFlag := flag.NewFlagSet("queryFlag", flag.ContinueOnError)
+	qFlag.SetOutput(io.Discard)
+	qFlag.StringVar(&args.query, "query", "",
+		"Grab the declarations matching a query, for example (function_declaration name: \"main\")")
+	qFlag.IntVar(&args.occurrence, "occurrence", 0,
+		"Grab only the Nth declaration matching the query")
+
+	return qFlag
+}
+
+// Extract parses the cinj command arguments and returns the source of every
+// declaration matching the query, separated by blank lines
+func (e queryExtractor) Extract(src Source, args []string) (Snippet, error) {
+	qArgs := newQueryArgs()
+
+	err := newQueryFlagSet(qArgs).Parse(args)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	if qArgs.occurrence < 0 {
+		return Snippet{}, errors.New("The occurrence argument must be 1 or greater")
+	}
+
+	content := string(src.Content)
+	if qArgs.query == "" {
+		return wholeFile(src, e.FileType()), nil
+	}
+
+	pattern, err := clike.ParseQuery(qArgs.query)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	root := clike.Parse(content, e.language)
+	matches := pattern.Matches(root)
+	if len(matches) == 0 {
+		return Snippet{}, fmt.Errorf("Nothing matches the query %s, found kinds %s",
+			qArgs.query, strings.Join(clike.Kinds(root), ", "))
+	}
+
+	if qArgs.occurrence > 0 {
+		if qArgs.occurrence > len(matches) {
+			return Snippet{}, fmt.Errorf("Asked for occurrence %d of the query but found %d",
+				qArgs.occurrence, len(matches))
+		}
+		matches = matches[qArgs.occurrence-1 : qArgs.occurrence]
+	}
+
+	var text numberedText
+	for i, node := range matches {
+		if i > 0 {
+			text.add("\n", 0)
+		}
+		text.add(nodeText(content, node)+"\n", node.StartLine)
+	}
+
+	snippet := text.snippet(e.FileType())
+	if len(matches) == 1 {
+		snippet.Name = matches[0].Name
+	}
+	return snippet, nil
+}
+
+// nodeText returns the source of a node, starting from the beginning of its
+// first line so the indentation of the first line matches the others
+func nodeText(content string, node *clike.Node) string {
+	start := node.Start
+	for start > 0 && (content[start-1] == ' ' || content[start-1] == '\t') {
+		start--
+	}
+	return content[start:node.End]
+}
+
+func init() {
+	for _, language := range clike.Languages {
+		RegisterExtractor(queryExtractor{language: language}, language.Extensions...)
+	}
+}
@@ -0,0 +1,229 @@
+package cinj
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultRedaction replaces redacted text when the config does not set a
+// replacement
+const defaultRedaction = "<REDACTED>"
+
+// RedactionConfig sets up the redaction of the snippets, replacing secrets
+// and other text that must not end up in the new file
+type RedactionConfig struct {
+	// Builtin turns off the built-in detectors when it is false, see
+	// builtinDetectors. They are on when it is not set
+	Builtin *bool `json:"builtin"`
+	// Rules are redacted in every snippet
+	Rules []RedactionRule `json:"rules"`
+	// Replacement is written in place of redacted text, defaulting to
+	// <REDACTED>
+	Replacement string `json:"replacement"`
+}
+
+// RedactionRule is a regular expression whose matches are redacted. When the
+// expression has a group named secret only that group is replaced, so
+// `token = "(?P<secret>[^"]+)"` keeps the name of the token
+type RedactionRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// detector is a compiled redaction rule
+type detector struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// builtinDetectors find common secrets: cloud and service keys, bearer
+// tokens, private key blocks and password assignments
+var builtinDetectors = []detector{
+	{name: "aws-access-key-id", re: regexp.MustCompile(
+		`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|AIPA|ANPA|ANVA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-access-key", re: regexp.MustCompile(
+		`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`)},
+	{name: "bearer-token", re: regexp.MustCompile(
+		`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{16,}=*)`)},
+	{name: "github-token", re: regexp.MustCompile(
+		`\b(?:(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z\-_]{35}\b`)},
+	{name: "json-web-token", re: regexp.MustCompile(
+		`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{name: "private-key", re: regexp.MustCompile(
+		`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----(?P<secret>(?s:.*?))-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{name: "password-assignment", re: regexp.MustCompile(
+		`(?i)\b(?:password|passwd|pwd|secret)["']?\s*[:=]\s*["'](?P<secret>[^"'\s]{4,})["']`)},
+}
+
+// detectors compiles the rules of the config, after the built-in detectors
+// unless they are turned off and `all` is not set
+func (rc RedactionConfig) detectors(all bool) ([]detector, error) {
+	replacement := rc.Replacement
+	if replacement == "" {
+		replacement = defaultRedaction
+	}
+
+	detectors := []detector{}
+	if rc.Builtin == nil || *rc.Builtin || all {
+		for _, d := range builtinDetectors {
+			d.replacement = replacement
+			detectors = append(detectors, d)
+		}
+	}
+
+	for i, rule := range rc.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid redaction rule %s: %w", rule.Name, err)
+		}
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		d := detector{name: name, re: re, replacement: rule.Replacement}
+		if d.replacement == "" {
+			d.replacement = replacement
+		}
+		detectors = append(detectors, d)
+	}
+	return detectors, nil
+}
+
+// redaction is a range of the content of a snippet to replace
+type redaction struct {
+	start       int
+	end         int
+	replacement string
+	detector    string
+}
+
+// find returns the ranges of the content matched by the detector, only the
+// secret group when the expression has one
+func (d detector) find(content string) []redaction {
+	secret := d.re.SubexpIndex("secret")
+	found := []redaction{}
+	for _, m := range d.re.FindAllStringSubmatchIndex(content, -1) {
+		start, end := m[0], m[1]
+		if secret > 0 && m[2*secret] >= 0 {
+			start, end = m[2*secret], m[2*secret+1]
+		}
+		if start == end {
+			continue
+		}
+		found = append(found, redaction{start, end, d.replacement, d.name})
+	}
+	return found
+}
+
+// redact replaces the matches of the detectors in the content of a snippet
+// and of its parts. Redacted text that spans lines is replaced on a single
+// line, the source line numbers of the other lines are kept
+func redact(snippet Snippet, detectors []detector) Snippet {
+	if len(snippet.Parts) > 0 {
+		parts := make([]Snippet, len(snippet.Parts))
+		for i, part := range snippet.Parts {
+			parts[i] = redact(part, detectors)
+		}
+		snippet.Parts = parts
+		return snippet
+	}
+
+	found := []redaction{}
+	for _, d := range detectors {
+		found = append(found, d.find(snippet.Content)...)
+	}
+	if len(found) == 0 {
+		return snippet
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+
+	numbers, err := snippet.sourceLines()
+	if err != nil {
+		numbers = nil
+	}
+
+	// lineOf is the index of the source line every byte of the new content
+	// comes from, used to number the new lines
+	var sb strings.Builder
+	lineOf := []int{}
+	line, pos := 0, 0
+	copyTo := func(end int) {
+		for ; pos < end; pos++ {
+			sb.WriteByte(snippet.Content[pos])
+			lineOf = append(lineOf, line)
+			if snippet.Content[pos] == '\n' {
+				line++
+			}
+		}
+	}
+	for _, r := range found {
+		if r.start < pos {
+			// overlaps a redaction that was already made
+			continue
+		}
+		copyTo(r.start)
+		sb.WriteString(r.replacement)
+		for i := 0; i < len(r.replacement); i++ {
+			lineOf = append(lineOf, line)
+		}
+		line += strings.Count(snippet.Content[r.start:r.end], "\n")
+		pos = r.end
+	}
+	copyTo(len(snippet.Content))
+
+	content := sb.String()
+	if numbers != nil {
+		newNumbers := []int{}
+		start := 0
+		for start < len(content) {
+			if idx := lineOf[start]; idx < len(numbers) {
+				newNumbers = append(newNumbers, numbers[idx])
+			} else {
+				newNumbers = append(newNumbers, 0)
+			}
+			end := strings.IndexByte(content[start:], '\n')
+			if end < 0 {
+				break
+			}
+			start += end + 1
+		}
+		snippet.LineNumbers = newNumbers
+		snippet.StartLine, snippet.EndLine = lineSpan(content, newNumbers)
+	}
+	snippet.Content = content
+	return snippet
+}
+
+// checkSecrets returns an error naming every match of the detectors left in
+// the snippet, counting lines from 1 at the first line of the snippet
+func checkSecrets(snippet Snippet, detectors []detector) error {
+	findings := []string{}
+	var check func(s Snippet)
+	check = func(s Snippet) {
+		for _, part := range s.Parts {
+			check(part)
+		}
+		for _, d := range detectors {
+			for _, r := range d.find(s.Content) {
+				if s.Content[r.start:r.end] == r.replacement {
+					// already redacted
+					continue
+				}
+				line := strings.Count(s.Content[:r.start], "\n") + 1
+				findings = append(findings, fmt.Sprintf("%s on line %d", r.detector, line))
+			}
+		}
+	}
+	check(snippet)
+
+	if len(findings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Found unredacted secrets: %s", strings.Join(findings, ", "))
+}
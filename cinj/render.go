@@ -0,0 +1,432 @@
+package cinj
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Fence styles for the code blocks written around snippets
+const (
+	fenceBacktick = "backtick"
+	fenceTilde    = "tilde"
+)
+
+// renderArgs are the arguments of a cinj command that change how the
+// snippet is written, shared by every file type
+type renderArgs struct {
+	fence           string
+	fenceID         string
+	fenceClass      listFlag
+	fenceAttr       listFlag
+	lineNumbers     bool
+	startFrom       bool
+	caption         bool
+	noCaption       bool
+	captionFormat   string
+	captionPosition string
+	highlight       string
+	highlightMatch  string
+	dedent          bool
+	tabs            int
+	trim            bool
+	crlf            string
+	elideLines      string
+	elideRegion     listFlag
+	elideBodies     bool
+	stripComments   bool
+	stripDocstrings bool
+	from            string
+	to              string
+	fromExclusive   bool
+	toExclusive     bool
+	replace         listFlag
+	eachHeading     string
+	// flavor is the output flavor from the config, it is not an argument
+	flavor string
+	// gutter is set when the output format shows line numbers next to the
+	// code, it is not an argument
+	gutter bool
+}
+
+func newRenderArgs() *renderArgs {
+	return &renderArgs{
+		fence:           fenceBacktick,
+		fenceID:         "",
+		fenceClass:      listFlag{},
+		fenceAttr:       listFlag{},
+		lineNumbers:     false,
+		startFrom:       false,
+		caption:         false,
+		noCaption:       false,
+		captionFormat:   "",
+		captionPosition: "",
+		highlight:       "",
+		highlightMatch:  "",
+		dedent:          false,
+		tabs:            0,
+		trim:            false,
+		crlf:            crlfKeep,
+		elideLines:      "",
+		elideRegion:     listFlag{},
+		elideBodies:     false,
+		stripComments:   false,
+		stripDocstrings: false,
+		from:            "",
+		to:              "",
+		fromExclusive:   false,
+		toExclusive:     false,
+		replace:         listFlag{},
+		eachHeading:     "",
+		flavor:          FlavorPandoc,
+	}
+}
+
+// listFlag is a flag that can be given more than once, keeping every value
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// newRenderFlagSet returns the flag set used to parse the rendering
+// arguments of a cinj command into `args`
+func newRenderFlagSet(args *renderArgs) *flag.FlagSet {
+	renderFlag := flag.NewFlagSet("renderFlag", flag.ContinueOnError)
+	renderFlag.SetOutput(io.Discard)
+	renderFlag.StringVar(&args.fence, "fence", fenceBacktick,
+		"Characters of the code block fence: backtick or tilde")
+	renderFlag.StringVar(&args.fenceID, "fence-id", "",
+		"Identifier added to the code block attributes, for example lst:init")
+	renderFlag.Var(&args.fenceClass, "fence-class",
+		"Class added to the code block attributes, for example numberLines")
+	renderFlag.Var(&args.fenceAttr, "fence-attr",
+		"Attribute added to the code block attributes, for example startFrom=42")
+	renderFlag.BoolVar(&args.lineNumbers, "line-numbers", false,
+		"Prefix each line with its line number in the source file")
+	renderFlag.BoolVar(&args.startFrom, "start-from", false,
+		"Number the code block lines from the first source line, using the pandoc numberLines class")
+	renderFlag.BoolVar(&args.caption, "caption", false,
+		"Write a caption with the snippet")
+	renderFlag.BoolVar(&args.noCaption, "no-caption", false,
+		"Do not write a caption, even when the config captions every snippet")
+	renderFlag.StringVar(&args.captionFormat, "caption-format", "",
+		"Format of the caption as a text/template, for example \"{{.Name}} in {{.Path}}\"")
+	renderFlag.StringVar(&args.captionPosition, "caption-position", "",
+		"Where the caption goes: above or below the code block")
+	renderFlag.StringVar(&args.highlight, "highlight", "",
+		"Highlight lines of the snippet, counting from 1, for example 3,7-9")
+	renderFlag.StringVar(&args.highlightMatch, "highlight-match", "",
+		"Highlight the lines of the snippet matching a regular expression")
+	renderFlag.BoolVar(&args.dedent, "dedent", false,
+		"Remove the indentation shared by every line of the snippet")
+	renderFlag.IntVar(&args.tabs, "tabs", 0,
+		"Expand tabs into spaces, with a tab stop every N columns")
+	renderFlag.BoolVar(&args.trim, "trim", false,
+		"Remove the blank lines at the start and end of the snippet")
+	renderFlag.StringVar(&args.crlf, "crlf", crlfKeep,
+		"Line endings of the snippet: keep or lf, which turns CRLF and CR line endings into LF")
+	renderFlag.StringVar(&args.elideLines, "elide", "",
+		"Replace lines of the snippet with a placeholder, counting from 1, for example 12-40")
+	renderFlag.Var(&args.elideRegion, "elide-region",
+		"Replace a region between # region NAME and # endregion comments with a placeholder")
+	renderFlag.BoolVar(&args.elideBodies, "elide-bodies", false,
+		"Replace the bodies of the functions nested in the snippet, such as methods, with a placeholder")
+	renderFlag.BoolVar(&args.stripComments, "strip-comments", false,
+		"Remove the comments of the snippet")
+	renderFlag.BoolVar(&args.stripDocstrings, "strip-docstrings", false,
+		"Remove the docstrings of the snippet, or the doc comments in C-like languages")
+	renderFlag.StringVar(&args.from, "from", "",
+		"Start the snippet at the first line matching a regular expression, for example /^int main/")
+	renderFlag.StringVar(&args.to, "to", "",
+		"End the snippet at the next line matching a regular expression, for example /^}/")
+	renderFlag.BoolVar(&args.fromExclusive, "from-exclusive", false,
+		"Leave out the line matching --from")
+	renderFlag.BoolVar(&args.toExclusive, "to-exclusive", false,
+		"Leave out the line matching --to")
+	renderFlag.Var(&args.replace, "replace",
+		"Substitution applied to every line of the snippet, for example s/old/new/g")
+	renderFlag.StringVar(&args.eachHeading, "each-heading", "",
+		"Heading written above the snippet of every file, as a text/template, for example \"### {{.Base}}\"")
+
+	return renderFlag
+}
+
+// splitRenderArgs separates the rendering arguments of a cinj command from
+// the arguments passed to the extractor
+func splitRenderArgs(args []string) (render []string, rest []string) {
+	return splitFlags(newRenderFlagSet(newRenderArgs()), args)
+}
+
+// splitFlags separates the arguments defined in a flag set, along with their
+// values, from the other arguments
+func splitFlags(fs *flag.FlagSet, args []string) (matched []string, rest []string) {
+	matched = []string{}
+	rest = []string{}
+
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			rest = append(rest, args[i])
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		matched = append(matched, args[i])
+		if !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			matched = append(matched, args[i+1])
+			i++
+		}
+	}
+
+	return matched, rest
+}
+
+// isBoolFlag reports whether a flag can be given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// parseRenderArgs uses the flag package to parse the rendering arguments
+// split off by splitRenderArgs
+func parseRenderArgs(args []string) (*renderArgs, error) {
+	renderArgs := newRenderArgs()
+
+	err := newRenderFlagSet(renderArgs).Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch renderArgs.fence {
+	case fenceBacktick, fenceTilde:
+	default:
+		return nil, fmt.Errorf("Unknown fence %s, expected backtick or tilde",
+			renderArgs.fence)
+	}
+
+	if err := checkCaptionPosition(renderArgs.captionPosition); err != nil {
+		return nil, err
+	}
+
+	if _, err := parseLineList(renderArgs.highlight); err != nil {
+		return nil, err
+	}
+	if _, err := parseLineList(renderArgs.elideLines); err != nil {
+		return nil, err
+	}
+
+	for _, expr := range []string{renderArgs.from, renderArgs.to} {
+		if expr == "" {
+			continue
+		}
+		if _, err := parseSlashed(expr); err != nil {
+			return nil, err
+		}
+	}
+	for _, value := range renderArgs.replace {
+		if _, err := parseSubstitution(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if renderArgs.eachHeading != "" {
+		if _, err := parseHeadingFormat(renderArgs.eachHeading); err != nil {
+			return nil, err
+		}
+	}
+
+	if renderArgs.tabs < 0 {
+		return nil, errors.New("The tabs argument must be 1 or greater")
+	}
+	switch renderArgs.crlf {
+	case crlfKeep, crlfLF:
+	default:
+		return nil, fmt.Errorf("Unknown line ending %s, expected keep or lf",
+			renderArgs.crlf)
+	}
+
+	for _, attr := range renderArgs.fenceAttr {
+		if key, _, found := strings.Cut(attr, "="); !found || key == "" {
+			return nil, fmt.Errorf("Invalid fence attribute %s, expected key=value",
+				attr)
+		}
+	}
+
+	return renderArgs, nil
+}
+
+// fenceFor returns the fence of the code block for the content. The fence is
+// longer than any run of the fence character in the content, so the content
+// can hold code blocks of its own
+func (r renderArgs) fenceFor(content string) string {
+	char := "`"
+	if r.fence == fenceTilde {
+		char = "~"
+	}
+
+	longest := 0
+	for _, run := range regexp.MustCompile(regexp.QuoteMeta(char)+"+").
+		FindAllString(content, -1) {
+		longest = max(longest, len(run))
+	}
+
+	return strings.Repeat(char, max(3, longest+1))
+}
+
+// codeBlock is a snippet ready to be written in an output format, with the
+// rendering arguments worked out
+type codeBlock struct {
+	Snippet
+	render       renderArgs
+	caption      string
+	captionBelow bool
+	highlighted  []int // lines to highlight, counting from 1
+	startFrom    int   // first line number, 0 unless --start-from is given
+	// lineNumbers holds the source line of every line for formats with a
+	// gutter when --line-numbers is given, nil otherwise
+	lineNumbers []int
+}
+
+// codeBlock works out the rendering arguments for a snippet. The lines to
+// highlight are found before line numbers are added to the content, or kept
+// apart from it for output formats with a gutter
+func (r renderArgs) codeBlock(snippet Snippet, caption string) (codeBlock, error) {
+	block := codeBlock{
+		render:       r,
+		caption:      caption,
+		captionBelow: r.captionPosition == captionBelow,
+	}
+
+	var err error
+	if !snippet.Raw {
+		block.highlighted, err = r.highlightedLines(snippet)
+		if err != nil {
+			return block, err
+		}
+	}
+
+	if r.startFrom {
+		lines, err := snippet.sourceLines()
+		if err != nil {
+			return block, err
+		}
+		block.startFrom = snippet.StartLine
+		if len(lines) > 0 && lines[0] > 0 {
+			block.startFrom = lines[0]
+		}
+	}
+
+	if r.lineNumbers && r.gutter {
+		block.lineNumbers, err = snippet.sourceLines()
+		if err != nil {
+			return block, err
+		}
+	} else if r.lineNumbers {
+		snippet, err = numberLines(snippet)
+		if err != nil {
+			return block, err
+		}
+	}
+
+	block.Snippet = snippet
+	return block, nil
+}
+
+// infoString returns the text written after the opening fence. Without any
+// attributes it is the language, otherwise a pandoc attribute block such as
+// {#lst:init .python .numberLines startFrom="42"}. The {{lines}} placeholder
+// in attribute values is replaced with the source lines of the snippet.
+// Highlighted lines are marked in the style of the output flavor
+func (r renderArgs) infoString(block codeBlock) string {
+	classes := append([]string{}, r.fenceClass...)
+	attributes := append([]string{}, r.fenceAttr...)
+
+	if block.startFrom > 0 {
+		classes = append(classes, "numberLines")
+		attributes = append(attributes, fmt.Sprintf("startFrom=%d", block.startFrom))
+	}
+
+	language := block.Language.String()
+	hasAttributes := r.fenceID != "" || len(classes) > 0 || len(attributes) > 0
+
+	if len(block.highlighted) > 0 {
+		switch r.flavor {
+		case FlavorVitePress, FlavorDocusaurus:
+			meta := "{" + formatLineRanges(block.highlighted, ",", "-") + "}"
+			if r.flavor == FlavorDocusaurus {
+				meta = " " + meta
+			}
+			if !hasAttributes {
+				return language + meta
+			}
+			return language + meta + " " +
+				r.attributeBlock(Plain, classes, attributes, block.Snippet)
+		case FlavorMkDocs:
+			hlLines := `hl_lines="` + formatLineRanges(block.highlighted, " ", "-") + `"`
+			if !hasAttributes {
+				return strings.TrimSpace(language + " " + hlLines)
+			}
+			attributes = append(attributes, hlLines)
+		default:
+			attributes = append(attributes,
+				`hl_lines="`+formatLineRanges(block.highlighted, " ", "-")+`"`)
+		}
+		hasAttributes = true
+	}
+
+	if !hasAttributes {
+		return language
+	}
+	return r.attributeBlock(block.Language, classes, attributes, block.Snippet)
+}
+
+// attributeBlock returns a pandoc attribute block holding the identifier,
+// the language and the classes and attributes of a code block
+func (r renderArgs) attributeBlock(
+	language Filetype,
+	classes []string,
+	attributes []string,
+	snippet Snippet,
+) string {
+	attrs := []string{}
+	if r.fenceID != "" {
+		attrs = append(attrs, "#"+strings.TrimPrefix(r.fenceID, "#"))
+	}
+	if language != Plain {
+		attrs = append(attrs, "."+language.String())
+	}
+	for _, class := range classes {
+		for _, name := range strings.Fields(class) {
+			attrs = append(attrs, "."+strings.TrimPrefix(name, "."))
+		}
+	}
+	for _, attr := range attributes {
+		key, value, _ := strings.Cut(attr, "=")
+		value = strings.ReplaceAll(value, "{{lines}}", snippet.lineRange())
+		attrs = append(attrs, key+"="+quoteAttr(value))
+	}
+
+	return "{" + strings.Join(attrs, " ") + "}"
+}
+
+// quoteAttr quotes an attribute value for an attribute block, unless the
+// value is already quoted
+func quoteAttr(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
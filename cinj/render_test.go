@@ -0,0 +1,157 @@
+package cinj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitRenderArgs(t *testing.T) {
+	render, rest := splitRenderArgs([]string{
+		"--class=Test", "--fence", "tilde", "--fence-class=numberLines",
+		"--function", "run", "--fence-attr=startFrom=42",
+	})
+
+	if got := strings.Join(render, " "); got != "--fence tilde --fence-class=numberLines --fence-attr=startFrom=42" {
+		t.Fatalf("wrong render arguments, got %q", got)
+	}
+	if got := strings.Join(rest, " "); got != "--class=Test --function run" {
+		t.Fatalf("wrong extractor arguments, got %q", got)
+	}
+}
+
+// infoString works out the code block of a snippet and returns its info string
+func infoString(r *renderArgs, snippet Snippet) (string, error) {
+	block, err := r.codeBlock(snippet, "")
+	if err != nil {
+		return "", err
+	}
+	return r.infoString(block), nil
+}
+
+func TestFenceFor(t *testing.T) {
+	tests := []struct {
+		fence    string
+		content  string
+		expected string
+	}{
+		{fenceBacktick, "print(1)\n", "```"},
+		{fenceBacktick, "```python\nprint(1)\n```\n", "````"},
+		{fenceBacktick, "a ````` b\n", "``````"},
+		{fenceTilde, "```python\n```\n", "~~~"},
+		{fenceTilde, "~~~~\n", "~~~~~"},
+	}
+
+	for i, tt := range tests {
+		r := newRenderArgs()
+		r.fence = tt.fence
+		if got := r.fenceFor(tt.content); got != tt.expected {
+			t.Fatalf("tests[%d] - wrong fence. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	r, err := parseRenderArgs([]string{
+		"--fence-id=lst:init", "--fence-class=numberLines",
+		"--fence-attr=startFrom=42", `--fence-attr=caption=Init "main"`,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `{#lst:init .python .numberLines startFrom="42" caption="Init \"main\""}`
+	if got, _ := infoString(r, Snippet{Language: Python}); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+
+	if got, _ := infoString(newRenderArgs(), Snippet{Language: Python}); got != "python" {
+		t.Fatalf("expected the language without attributes, got %s", got)
+	}
+
+	for _, args := range [][]string{{"--fence=quote"}, {"--fence-attr=novalue"}} {
+		if _, err := parseRenderArgs(args); err == nil {
+			t.Fatalf("%v - expected an error", args)
+		}
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	r, err := parseRenderArgs([]string{"--start-from", "--fence-attr=caption=lines {{lines}}"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var text numberedText
+	text.add("class A:\n", 8)
+	text.add("    ...\n", 0)
+	text.add("    def run(self):\n        pass\n", 40)
+	snippet := text.snippet(Python)
+
+	expected := `{.python .numberLines caption="lines 8–41" startFrom="8"}`
+	if got, err := infoString(r, snippet); err != nil || got != expected {
+		t.Fatalf("expected %s, got %s (%v)", expected, got, err)
+	}
+
+	numbered, err := numberLines(snippet)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected = " 8  class A:\n        ...\n40      def run(self):\n41          pass\n"
+	if numbered.Content != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, numbered.Content)
+	}
+
+	if _, err := numberLines(Snippet{Content: "x\n"}); err == nil {
+		t.Fatal("expected an error for a snippet without line numbers")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	snippet := Snippet{
+		Content:  "def f(x):\n    y = x\n    return y\n\ndef g():\n    return 2\n",
+		Language: Python,
+	}
+
+	tests := []struct {
+		flavor   string
+		args     []string
+		expected string
+	}{
+		{FlavorPandoc, []string{"--highlight=1,4-5"}, `{.python hl_lines="1 4-5"}`},
+		{FlavorPandoc, []string{"--highlight-match=return", "--fence-id=f"},
+			`{#f .python hl_lines="3 6"}`},
+		{FlavorMkDocs, []string{"--highlight=2,3", "--highlight-match=^def g"},
+			`python hl_lines="2-3 5"`},
+		{FlavorVitePress, []string{"--highlight=3,1"}, "python{1,3}"},
+		{FlavorDocusaurus, []string{"--highlight=2-4"}, "python {2-4}"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		r.flavor = tt.flavor
+		got, err := infoString(r, snippet)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - expected %s, got %s", i, tt.expected, got)
+		}
+	}
+
+	for _, args := range [][]string{{"--highlight=9"}, {"--highlight=1-999999999"}, {"--highlight-match=missing"}} {
+		r, err := parseRenderArgs(args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err := infoString(r, snippet); err == nil {
+			t.Fatalf("%v - expected an error", args)
+		}
+	}
+
+	if _, err := parseRenderArgs([]string{"--highlight=4-2"}); err == nil {
+		t.Fatal("expected an error for a backwards range")
+	}
+}
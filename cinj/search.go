@@ -0,0 +1,253 @@
+package cinj
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// substitution is a sed style s/old/new/flags argument of --replace
+type substitution struct {
+	re          *regexp.Regexp
+	replacement string
+	global      bool
+}
+
+// parseSlashed parses a regular expression written between slashes, such as
+// /^int main/ or /todo/i, where i makes the expression ignore case
+func parseSlashed(value string) (*regexp.Regexp, error) {
+	parts, err := splitDelimited(value, 1)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expression %s, expected a regular expression between slashes such as /^int main/",
+			value)
+	}
+	if parts[0] != "/" {
+		return nil, fmt.Errorf("Invalid expression %s, expected a regular expression between slashes such as /^int main/",
+			value)
+	}
+	return compileWithFlags(parts[1], parts[2], "i")
+}
+
+// parseSubstitution parses a --replace argument such as s/old/new/g, where
+// g replaces every match of a line instead of the first one and i ignores
+// case. Any character can be the delimiter, as in s|/usr|/opt|
+func parseSubstitution(value string) (substitution, error) {
+	if !strings.HasPrefix(value, "s") {
+		return substitution{}, fmt.Errorf("Invalid replacement %s, expected s/old/new/ with optional g and i flags",
+			value)
+	}
+	parts, err := splitDelimited(value[1:], 2)
+	if err != nil {
+		return substitution{}, fmt.Errorf("Invalid replacement %s, expected s/old/new/ with optional g and i flags",
+			value)
+	}
+
+	re, err := compileWithFlags(parts[1], parts[3], "gi")
+	if err != nil {
+		return substitution{}, err
+	}
+	return substitution{
+		re:          re,
+		replacement: expandTemplate(parts[2]),
+		global:      strings.Contains(parts[3], "g"),
+	}, nil
+}
+
+// expandTemplate turns a sed replacement into a regexp.Expand template. \1
+// to \9 are the groups of the match, & is the whole match, \& and \\ are a
+// literal & and backslash, and a $ is always written as it is
+func expandTemplate(replacement string) string {
+	var sb strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		ch := replacement[i]
+		switch {
+		case ch == '$':
+			sb.WriteString("$$")
+		case ch == '&':
+			sb.WriteString("${0}")
+		case ch == '\\' && i+1 < len(replacement):
+			next := replacement[i+1]
+			switch {
+			case next >= '0' && next <= '9':
+				sb.WriteString("${" + string(next) + "}")
+			case next == '&' || next == '\\':
+				sb.WriteByte(next)
+			default:
+				sb.WriteByte(ch)
+				continue
+			}
+			i++
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+// splitDelimited splits a value such as /a/b/flags on its first character,
+// returning the delimiter, the `count` fields between delimiters and the
+// text after the last one. A delimiter escaped with a backslash is part of
+// its field
+func splitDelimited(value string, count int) ([]string, error) {
+	if value == "" {
+		return nil, errors.New("Missing delimiter")
+	}
+	delim := value[:1]
+	fields := []string{delim}
+
+	var sb strings.Builder
+	rest := value[1:]
+	for len(fields) <= count {
+		if rest == "" {
+			return nil, fmt.Errorf("Missing closing %s", delim)
+		}
+		switch {
+		case strings.HasPrefix(rest, `\`+delim):
+			sb.WriteString(delim)
+			rest = rest[1+len(delim):]
+		case strings.HasPrefix(rest, `\`) && len(rest) > 1:
+			sb.WriteString(rest[:2])
+			rest = rest[2:]
+		case strings.HasPrefix(rest, delim):
+			fields = append(fields, sb.String())
+			sb.Reset()
+			rest = rest[len(delim):]
+		default:
+			sb.WriteByte(rest[0])
+			rest = rest[1:]
+		}
+	}
+	return append(fields, rest), nil
+}
+
+// compileWithFlags compiles a regular expression with the flags written
+// after it, which must be among `allowed`
+func compileWithFlags(expr string, flags string, allowed string) (*regexp.Regexp, error) {
+	for _, f := range flags {
+		if !strings.ContainsRune(allowed, f) {
+			return nil, fmt.Errorf("Unknown flag %c after /%s/, expected one of %s",
+				f, expr, allowed)
+		}
+	}
+	if strings.Contains(flags, "i") {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regular expression %s: %w", expr, err)
+	}
+	return re, nil
+}
+
+// search narrows a snippet down to the lines between the first line matching
+// --from and the next line matching --to, then applies the --replace
+// substitutions to every line that is left
+func (r renderArgs) search(snippet Snippet) (Snippet, error) {
+	if r.from == "" && r.to == "" && len(r.replace) == 0 {
+		return snippet, nil
+	}
+
+	newline := strings.HasSuffix(snippet.Content, "\n")
+	lines := strings.Split(strings.TrimSuffix(snippet.Content, "\n"), "\n")
+	numbers, err := snippet.sourceLines()
+	if err != nil || len(numbers) != len(lines) {
+		numbers = nil
+	}
+
+	start, end := 0, len(lines)
+	if r.from != "" {
+		re, err := parseSlashed(r.from)
+		if err != nil {
+			return snippet, err
+		}
+		start = matchingLine(lines, re, 0)
+		if start < 0 {
+			return snippet, fmt.Errorf("No line of the snippet matches --from=%s", r.from)
+		}
+		if r.fromExclusive {
+			start++
+		}
+	}
+	if r.to != "" {
+		re, err := parseSlashed(r.to)
+		if err != nil {
+			return snippet, err
+		}
+		// the end is looked for after the line matching --from, so the same
+		// expression can mark both ends
+		searchFrom := start
+		if r.from != "" && !r.fromExclusive {
+			searchFrom++
+		}
+		end = matchingLine(lines, re, searchFrom)
+		if end < 0 {
+			return snippet, fmt.Errorf("No line of the snippet after the start matches --to=%s", r.to)
+		}
+		if !r.toExclusive {
+			end++
+		}
+	}
+	if start > end {
+		start = end
+	}
+
+	kept := []string{}
+	keptNumbers := []int{}
+	for i := start; i < end; i++ {
+		line := lines[i]
+		for _, value := range r.replace {
+			sub, err := parseSubstitution(value)
+			if err != nil {
+				return snippet, err
+			}
+			line = sub.apply(line)
+		}
+
+		// a replacement can add lines, they are not from the source
+		for j, part := range strings.Split(line, "\n") {
+			kept = append(kept, part)
+			if numbers != nil && j == 0 {
+				keptNumbers = append(keptNumbers, numbers[i])
+			} else {
+				keptNumbers = append(keptNumbers, 0)
+			}
+		}
+	}
+
+	snippet.Content = strings.Join(kept, "\n")
+	if newline && len(kept) > 0 {
+		snippet.Content += "\n"
+	}
+	if numbers != nil {
+		snippet.LineNumbers = keptNumbers
+		snippet.StartLine, snippet.EndLine = lineSpan(snippet.Content, keptNumbers)
+	}
+	return snippet, nil
+}
+
+// matchingLine returns the index of the first line from `start` matching the
+// expression, or -1 when none match
+func matchingLine(lines []string, re *regexp.Regexp, start int) int {
+	for i := start; i < len(lines); i++ {
+		if re.MatchString(lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// apply replaces the first match of the substitution in a line, or every
+// match with the g flag
+func (s substitution) apply(line string) string {
+	if s.global {
+		return s.re.ReplaceAllString(line, s.replacement)
+	}
+	loc := s.re.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return line
+	}
+	var dst []byte
+	dst = s.re.ExpandString(dst, s.replacement, line, loc)
+	return line[:loc[0]] + string(dst) + line[loc[1]:]
+}
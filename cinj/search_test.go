@@ -0,0 +1,94 @@
+package cinj
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	c := `#include <stdio.h>
+
+int helper(void) {
+    return 1;
+}
+
+int main(void) {
+    printf("old old\n");
+    return 0;
+}
+`
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--from=/^int main/", "--to=/^}/"},
+			"int main(void) {\n    printf(\"old old\\n\");\n    return 0;\n}\n"},
+		{[]string{"--from=/^int main/", "--to=/^}/", "--from-exclusive", "--to-exclusive"},
+			"    printf(\"old old\\n\");\n    return 0;\n"},
+		{[]string{"--to=/^$/", "--to-exclusive"}, "#include <stdio.h>\n"},
+		{[]string{"--from=/PRINTF/i", "--to=/return/", "--replace=s/old/new/"},
+			"    printf(\"new old\\n\");\n    return 0;\n"},
+		{[]string{"--from=/printf/", "--to=/return/", "--to-exclusive", "--replace=s|(o)ld|n\\1w|g"},
+			"    printf(\"now now\\n\");\n"},
+		{[]string{"--from=/printf/", "--to=/return/", "--to-exclusive", "--replace=s/old/cost: $5/"},
+			"    printf(\"cost: $5 old\\n\");\n"},
+		{[]string{"--from=/printf/", "--to=/return/", "--to-exclusive", "--replace=s/o(l)d/[&$1]/g"},
+			"    printf(\"[old$1] [old$1]\\n\");\n"},
+		{[]string{"--from=/printf/", "--to=/return/", "--to-exclusive", "--replace=s/old/a\\&b\\\\/"},
+			"    printf(\"a&b\\ old\\n\");\n"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := r.transform(Snippet{Content: c, Language: "c", StartLine: 1})
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected %q, got %q", i, tt.expected, got.Content)
+		}
+	}
+
+	r, _ := parseRenderArgs([]string{"--from=/^int main/", "--to=/^}/"})
+	got, _ := r.transform(Snippet{Content: c, Language: "c", StartLine: 1})
+	if got.StartLine != 7 || got.EndLine != 10 {
+		t.Fatalf("expected lines 7-10, got %d-%d", got.StartLine, got.EndLine)
+	}
+
+	r, _ = parseRenderArgs([]string{"--from=/^class/"})
+	if _, err := r.transform(Snippet{Content: c, Language: "c"}); err == nil {
+		t.Fatal("expected an error when no line matches --from")
+	}
+	for _, args := range [][]string{{"--from=^int"}, {"--to=/x/g"}, {"--replace=s/a/b"}, {"--replace=s/(/b/"}} {
+		if _, err := parseRenderArgs(args); err == nil {
+			t.Fatalf("expected an error for %v", args)
+		}
+	}
+
+	// backslashes of an expression are kept inside of double quotes
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.c"), []byte(c), 0o644); err != nil {
+		t.Fatal(err.Error())
+	}
+	cj := Cinj{Filepath: filepath.Join(dir, "report.cinj")}
+	cmd, err := cj.getCinjCommand(`cinj{./main.c --from="/^int main\(/" --to="/^\}/" --replace="s/\"old/\\\\/"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	render, err := parseRenderArgs(cmd.SuppArgs)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	got, err = cj.getSnippet(cmd, *render)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "int main(void) {\n    printf(\\ old\\n\");\n    return 0;\n}\n"
+	if got.Content != expected {
+		t.Fatalf("expected %q, got %q", expected, got.Content)
+	}
+}
@@ -0,0 +1,28 @@
+package cinj
+
+// Snippet is the content found for a cinj command, ready to be written into
+// the new file
+type Snippet struct {
+	Content  string
+	Language Filetype
+	// Name is what was extracted, such as Client.fetch, used for captions
+	Name string
+	// StartLine and EndLine are the lines of the source file the content
+	// was taken from, starting from 1. They are 0 when not known
+	StartLine int
+	EndLine   int
+	// Commit is the short hash of the git commit the source was read from,
+	// empty when it was read from the working tree
+	Commit string
+	// LineNumbers holds the source line of every line of Content, with 0 for
+	// lines that are not from the source such as a separator. When nil the
+	// lines count up from StartLine
+	LineNumbers []int
+	// Raw snippets are written as they are instead of inside of a code block,
+	// used for content that is already Markdown
+	Raw bool
+	// Parts holds the snippets of a command that results in more than one
+	// block, such as the cells of a notebook. Content is ignored when a
+	// snippet has parts
+	Parts []Snippet
+}
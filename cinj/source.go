@@ -0,0 +1,150 @@
+package cinj
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type sourceArgs struct {
+	rev    string
+	sha256 string
+}
+
+func newSourceArgs() *sourceArgs {
+	return &sourceArgs{
+		rev:    "",
+		sha256: "",
+	}
+}
+
+// newSourceFlagSet returns the flag set used to parse the arguments of a
+// cinj command that choose where its files are read from into `args`. They
+// apply to every file of the command
+func newSourceFlagSet(args *sourceArgs) *flag.FlagSet {
+	sourceFlag := flag.NewFlagSet("sourceFlag", flag.ContinueOnError)
+	sourceFlag.SetOutput(io.Discard)
+	sourceFlag.StringVar(&args.rev, "rev", "",
+		"Read the files from a git revision, such as a tag, branch or commit, instead of the working tree")
+	sourceFlag.StringVar(&args.sha256, "sha256", "",
+		"Fail unless the SHA-256 of the included files is this hex encoded sum")
+
+	return sourceFlag
+}
+
+// splitRevision splits a path written as path@rev into the path and the git
+// revision. A path that exists as it is, such as icon@2x.png, has no
+// revision
+func splitRevision(path string) (string, string) {
+	if isURL(path) {
+		return path, ""
+	}
+	idx := strings.LastIndex(path, "@")
+	if idx <= 0 || idx == len(path)-1 {
+		return path, ""
+	}
+	rev := path[idx+1:]
+	if strings.ContainsAny(rev, `/\`) {
+		return path, ""
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, ""
+	}
+	return path[:idx], rev
+}
+
+// readSource reads a file included by a cinj command, from the working tree
+// or, when `rev` is set, from that revision of the git repository the file
+// is in. The short hash of the commit is returned along with the file. A
+// path inside of an archive, such as ./samples.zip!/main.py, reads the
+// member of the archive, and an http or https URL is fetched. When `sum` is
+// set the content must have that SHA-256
+func (c Cinj) readSource(path string, rev string, sum string) (Source, string, error) {
+	src, commit, err := c.readContent(path, rev, sum)
+	if err != nil {
+		return src, commit, err
+	}
+	if sum != "" {
+		if err := checkSum(src.Content, sum); err != nil {
+			return Source{}, "", fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return src, commit, nil
+}
+
+func (c Cinj) readContent(path string, rev string, sum string) (Source, string, error) {
+	if archive, member, ok := splitArchive(path); ok {
+		src, commit, err := c.readSource(archive, rev, "")
+		if err != nil {
+			return Source{}, "", err
+		}
+		content, err := readMember(archive, src.Content, member)
+		if err != nil {
+			return Source{}, "", err
+		}
+		return Source{Path: path, Content: content}, commit, nil
+	}
+
+	if isURL(path) {
+		if rev != "" {
+			return Source{}, "", fmt.Errorf("Can not read %s at git revision %s, it is a URL", path, rev)
+		}
+		content, err := c.fetch(path, sum)
+		if err != nil {
+			return Source{}, "", err
+		}
+		return Source{Path: path, Content: content}, "", nil
+	}
+
+	if rev == "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return Source{}, "", err
+		}
+		return Source{Path: path, Content: content}, "", nil
+	}
+
+	if strings.HasPrefix(rev, "-") {
+		return Source{}, "", fmt.Errorf("Invalid git revision %s", rev)
+	}
+	dir := filepath.Dir(path)
+	commit, err := git(dir, "rev-parse", "--short", "--verify", rev+"^{commit}")
+	if err != nil {
+		return Source{}, "", fmt.Errorf("Unknown git revision %s: %w", rev, err)
+	}
+	commit = strings.TrimSpace(commit)
+
+	content, err := git(dir, "cat-file", "blob", commit+":./"+filepath.Base(path))
+	if err != nil {
+		return Source{}, "", fmt.Errorf("Could not read %s at revision %s: %w",
+			filepath.Base(path), rev, err)
+	}
+	return Source{Path: path, Content: []byte(content)}, commit, nil
+}
+
+// git runs a git command in `dir` and returns its output. The error holds
+// the first line git wrote to stderr
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("git was not found on the PATH")
+		}
+		message, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if message == "" {
+			message = err.Error()
+		}
+		return "", errors.New(message)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,67 @@
+package cinj
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@b.c", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@b.c")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	run("init", "-q")
+	write("app.py", "def main():\n    return 1\n")
+	run("add", "app.py")
+	run("commit", "-q", "-m", "first")
+	run("tag", "v1.4")
+	commit := run("rev-parse", "--short", "HEAD")
+	write("app.py", "def main():\n    return 2\n")
+
+	src := filepath.Join(dir, "report.cinj")
+	write("report.cinj", "cinj{./app.py@v1.4 --function=main --caption}\n\n"+
+		"cinj{./app.py --rev=HEAD --function=main --caption-format=\"{{.Revision}} {{.Commit}}\"}\n\n"+
+		"cinj{./app.py --function=main}\n\n")
+
+	c := Cinj{Filepath: src, Newname: filepath.Join(dir, "report.md")}
+	if err := c.Run(); err != nil {
+		t.Fatal(err.Error())
+	}
+	got, err := os.ReadFile(c.Newname)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "Listing 1: `main` — app.py at " + commit + ", lines 1–2\n\n" +
+		"```python\ndef main():\n    return 1\n```\n" +
+		"HEAD " + commit + "\n\n" +
+		"```python\ndef main():\n    return 1\n```\n" +
+		"```python\ndef main():\n    return 2\n```\n"
+	if string(got) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	write("report.cinj", "cinj{./app.py@v9 --function=main}\n")
+	if err := c.Run(); err == nil || !strings.Contains(err.Error(), "Unknown git revision v9") {
+		t.Fatalf("expected an unknown revision error, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package cinj
+
+import (
+	"fmt"
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+	"github.com/TheDavo/cinj/lexers/python"
+)
+
+// strip removes the comments and docstrings of a snippet for --strip-comments
+// and --strip-docstrings, using the tokens of the lexer of its language, so
+// that a # inside of a Python string is left alone. Lines left blank by the
+// removal are dropped, and the source line numbers of the other lines are
+// kept
+func (r renderArgs) strip(snippet Snippet) (Snippet, error) {
+	if !r.stripComments && !r.stripDocstrings {
+		return snippet, nil
+	}
+
+	tokens := codeTokens(snippet.Content, snippet.Language)
+	if tokens == nil && strings.TrimSpace(snippet.Content) != "" {
+		return snippet, fmt.Errorf("Can not strip comments of %s snippets, there is no lexer for the language",
+			snippet.Language)
+	}
+
+	removed := []lex.Token{}
+	for i, tok := range tokens {
+		switch {
+		case tok.Type == python.COMMENT && r.stripComments:
+			if i == 0 && strings.HasPrefix(tok.Literal, "#!") {
+				// keep the shebang of a script
+				continue
+			}
+			removed = append(removed, tok)
+		case r.stripDocstrings && isDocstring(tokens, i, snippet.Language):
+			removed = append(removed, tok)
+		}
+	}
+	if len(removed) == 0 {
+		return snippet, nil
+	}
+
+	numbers, err := snippet.sourceLines()
+	if err != nil {
+		numbers = nil
+	}
+	snippet.Content, numbers = removeTokens(snippet.Content, removed, numbers)
+	if numbers != nil {
+		snippet.LineNumbers = numbers
+		snippet.StartLine, snippet.EndLine = lineSpan(snippet.Content, numbers)
+	}
+	return snippet, nil
+}
+
+// isDocstring reports whether the token at idx documents a declaration. In
+// Python it is a string that is the first statement of the snippet, or of a
+// class or function body. In other languages it is a doc comment such as
+// /** ... */, /// or //!
+func isDocstring(tokens []lex.Token, idx int, language Filetype) bool {
+	tok := tokens[idx]
+	if language != Python {
+		if tok.Type != python.COMMENT {
+			return false
+		}
+		return strings.HasPrefix(tok.Literal, "///") || strings.HasPrefix(tok.Literal, "//!") ||
+			(strings.HasPrefix(tok.Literal, "/**") && tok.Literal != "/**/")
+	}
+	if tok.Type != python.STRING {
+		return false
+	}
+
+	// the string must be a statement of its own, ending its line
+	end := tok.Line + strings.Count(tok.Literal, "\n")
+	if idx+1 < len(tokens) && tokens[idx+1].Type != python.COMMENT && tokens[idx+1].Line == end {
+		return false
+	}
+
+	prev := idx - 1
+	for prev >= 0 && tokens[prev].Type == python.COMMENT {
+		prev--
+	}
+	if prev < 0 {
+		return true
+	}
+	if tokens[prev].Literal != ":" || tokens[prev].Line == tok.Line {
+		return false
+	}
+
+	// the colon must end a def or class statement, found by walking back over
+	// its brackets to the keyword that starts it
+	depth := 0
+	for i := prev - 1; i >= 0; i-- {
+		switch tokens[i].Literal {
+		case ")", "]", "}":
+			depth++
+		case "(", "[", "{":
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		if tokens[i].Literal == ":" {
+			return false
+		}
+		if tokens[i].Type == python.KEYWORD {
+			switch tokens[i].Literal {
+			case "def", "class":
+				return true
+			case "if", "elif", "else", "for", "while", "with", "try", "except",
+				"finally", "match", "case", "lambda":
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// removeTokens removes the text of tokens from content. The spaces before a
+// removed token are removed with it, and lines that are left blank are
+// dropped along with their number in `numbers`, as are the blank lines
+// inside of a removed token such as a docstring
+func removeTokens(content string, tokens []lex.Token, numbers []int) (string, []int) {
+	// removed marks the bytes taken out of their line, and inside the bytes,
+	// newlines included, within a removed token
+	removed := make([]bool, len(content))
+	inside := make([]bool, len(content))
+	for _, tok := range tokens {
+		start := tok.StartPosition
+		for start > 0 && (content[start-1] == ' ' || content[start-1] == '\t') {
+			start--
+		}
+		for i := start; i < tok.EndPosition && i < len(content); i++ {
+			removed[i] = content[i] != '\n'
+			inside[i] = true
+		}
+	}
+
+	newline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if numbers != nil && len(numbers) != len(lines) {
+		numbers = nil
+	}
+
+	kept := []string{}
+	keptNumbers := []int{}
+	offset := 0
+	for i, line := range lines {
+		var sb strings.Builder
+		// a line is touched when it lies fully inside a removed token, from
+		// the newline before it to the newline ending it
+		touched := offset > 0 && inside[offset-1] &&
+			(offset+len(line) >= len(content) || inside[offset+len(line)])
+		for j := 0; j < len(line); j++ {
+			if removed[offset+j] {
+				touched = true
+				continue
+			}
+			sb.WriteByte(line[j])
+		}
+		offset += len(line) + 1
+
+		text := sb.String()
+		if touched {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			text = strings.TrimRight(text, " \t")
+		}
+		kept = append(kept, text)
+		if numbers != nil {
+			keptNumbers = append(keptNumbers, numbers[i])
+		}
+	}
+
+	result := strings.Join(kept, "\n")
+	if newline && len(kept) > 0 {
+		result += "\n"
+	}
+	if numbers == nil {
+		return result, nil
+	}
+	return result, keptNumbers
+}
@@ -0,0 +1,66 @@
+package cinj
+
+import (
+	"testing"
+)
+
+func TestStrip(t *testing.T) {
+	python := `"""Module docstring."""
+import os  # the os module
+
+class A:
+    """Class docstring."""
+    url = "http://x#y"  # keep the string
+
+    def f(self):
+        '''Method docstring,
+        on two lines.'''
+        # a comment
+        return "# not a comment"
+`
+	js := "/** Adds numbers. */\nfunction add(a, b) {\n  // sum\n  return `// ${a + b}`; /* inline */\n}\n"
+
+	tests := []struct {
+		snippet  Snippet
+		args     []string
+		expected string
+	}{
+		{Snippet{Content: python, Language: Python}, []string{"--strip-comments"},
+			"\"\"\"Module docstring.\"\"\"\nimport os\n\nclass A:\n    \"\"\"Class docstring.\"\"\"\n" +
+				"    url = \"http://x#y\"\n\n    def f(self):\n        '''Method docstring,\n" +
+				"        on two lines.'''\n        return \"# not a comment\"\n"},
+		{Snippet{Content: python, Language: Python}, []string{"--strip-docstrings"},
+			"import os  # the os module\n\nclass A:\n    url = \"http://x#y\"  # keep the string\n\n" +
+				"    def f(self):\n        # a comment\n        return \"# not a comment\"\n"},
+		{Snippet{Content: "def g():\n    \"\"\"Summary.\n\n    Details.\n    \"\"\"\n\n    return 1\n", Language: Python},
+			[]string{"--strip-docstrings"}, "def g():\n\n    return 1\n"},
+		{Snippet{Content: js, Language: Javascript}, []string{"--strip-comments"},
+			"function add(a, b) {\n  return `// ${a + b}`;\n}\n"},
+		{Snippet{Content: js, Language: Javascript}, []string{"--strip-docstrings"},
+			"function add(a, b) {\n  // sum\n  return `// ${a + b}`; /* inline */\n}\n"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := r.strip(tt.snippet)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i, tt.expected, got.Content)
+		}
+	}
+
+	r, _ := parseRenderArgs([]string{"--strip-comments"})
+	got, _ := r.strip(Snippet{Content: "# a\nx = 1\n# b\ny = 2\n", Language: Python, StartLine: 5})
+	if got.StartLine != 6 || got.EndLine != 8 || len(got.LineNumbers) != 2 || got.LineNumbers[1] != 8 {
+		t.Fatalf("expected lines 6 and 8, got %d-%d %v", got.StartLine, got.EndLine, got.LineNumbers)
+	}
+
+	if _, err := r.strip(Snippet{Content: "<!-- a -->\n", Language: "html"}); err == nil {
+		t.Fatal("expected an error for a language without a lexer")
+	}
+}
@@ -0,0 +1,152 @@
+package cinj
+
+import (
+	"strings"
+)
+
+// Line endings of the crlf argument
+const (
+	crlfKeep = "keep"
+	crlfLF   = "lf"
+)
+
+// transform applies the transform arguments to the content of a snippet and
+// of its parts, in the order search (--from, --to and --replace), strip,
+// whitespace (such as --dedent and --trim) and elide. The source line
+// numbers of the lines that are left are kept
+func (r renderArgs) transform(snippet Snippet) (Snippet, error) {
+	if len(snippet.Parts) > 0 {
+		parts := make([]Snippet, len(snippet.Parts))
+		for i, part := range snippet.Parts {
+			var err error
+			parts[i], err = r.transform(part)
+			if err != nil {
+				return snippet, err
+			}
+		}
+		snippet.Parts = parts
+		return snippet, nil
+	}
+	if snippet.Raw {
+		return snippet, nil
+	}
+	snippet, err := r.search(snippet)
+	if err != nil {
+		return snippet, err
+	}
+	snippet, err = r.strip(snippet)
+	if err != nil {
+		return snippet, err
+	}
+	return r.elide(r.whitespace(snippet))
+}
+
+// whitespace applies the whitespace arguments to the content of a snippet
+func (r renderArgs) whitespace(snippet Snippet) Snippet {
+	if !r.dedent && r.tabs == 0 && !r.trim && r.crlf != crlfLF {
+		return snippet
+	}
+
+	numbers, err := snippet.sourceLines()
+	if err != nil {
+		numbers = nil
+	}
+
+	content := snippet.Content
+	if r.crlf == crlfLF {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+		if strings.Contains(content, "\r") {
+			// lone CR line endings add lines the source numbers do not know
+			content = strings.ReplaceAll(content, "\r", "\n")
+			numbers = nil
+		}
+	}
+
+	newline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if numbers != nil && len(numbers) != len(lines) {
+		numbers = nil
+	}
+
+	if r.tabs > 0 {
+		for i, line := range lines {
+			lines[i] = expandTabs(line, r.tabs)
+		}
+	}
+	if r.dedent {
+		dedentLines(lines)
+	}
+	if r.trim {
+		start, end := 0, len(lines)
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		lines = lines[start:end]
+		if numbers != nil {
+			numbers = numbers[start:end]
+		}
+		newline = len(lines) > 0
+	}
+
+	snippet.Content = strings.Join(lines, "\n")
+	if newline {
+		snippet.Content += "\n"
+	}
+	snippet.LineNumbers = numbers
+	if numbers != nil && r.trim {
+		snippet.StartLine, snippet.EndLine = lineSpan(snippet.Content, numbers)
+	}
+	return snippet
+}
+
+// expandTabs replaces the tabs of a line with spaces up to the next tab stop
+func expandTabs(line string, tabs int) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var sb strings.Builder
+	column := 0
+	for _, ch := range line {
+		if ch == '\t' {
+			spaces := tabs - column%tabs
+			sb.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		}
+		sb.WriteRune(ch)
+		column++
+	}
+	return sb.String()
+}
+
+// dedentLines removes the leading whitespace shared by every line that is
+// not blank. Blank lines are emptied
+func dedentLines(lines []string) {
+	prefix := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+}
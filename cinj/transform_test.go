@@ -0,0 +1,51 @@
+package cinj
+
+import (
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	var text numberedText
+	text.add("\n    def f(self):\r\n", 9)
+	text.add("    \tif x:\n\n        return 1\n\n", 11)
+	snippet := text.snippet(Python)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--trim"}, "    def f(self):\r\n    \tif x:\n\n        return 1\n"},
+		{[]string{"--dedent", "--crlf=lf"},
+			"\ndef f(self):\n\tif x:\n\n    return 1\n\n"},
+		{[]string{"--tabs=4", "--dedent", "--trim", "--crlf=lf"},
+			"def f(self):\n    if x:\n\n    return 1\n"},
+		{[]string{"--tabs=8"}, "\n    def f(self):\r\n        if x:\n\n        return 1\n\n"},
+	}
+
+	for i, tt := range tests {
+		r, err := parseRenderArgs(tt.args)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := r.transform(snippet)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected %q, got %q", i, tt.expected, got.Content)
+		}
+	}
+
+	r, _ := parseRenderArgs([]string{"--trim"})
+	trimmed, _ := r.transform(snippet)
+	if trimmed.StartLine != 10 || trimmed.EndLine != 13 || trimmed.LineNumbers[0] != 10 {
+		t.Fatalf("expected lines 10-13, got %d-%d %v", trimmed.StartLine,
+			trimmed.EndLine, trimmed.LineNumbers)
+	}
+
+	for _, args := range [][]string{{"--tabs=-1"}, {"--crlf=cr"}} {
+		if _, err := parseRenderArgs(args); err == nil {
+			t.Fatalf("%v - expected an error", args)
+		}
+	}
+}
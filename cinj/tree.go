@@ -0,0 +1,378 @@
+package cinj
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VerbTree is the verb of a cinj command writing a listing of the files of a
+// directory, as in cinj{tree ./src --depth=2}
+const VerbTree = "tree"
+
+type treeArgs struct {
+	depth     int
+	exclude   listFlag
+	gitignore bool
+	hidden    bool
+	sizes     bool
+	lines     bool
+}
+
+func newTreeArgs() *treeArgs {
+	return &treeArgs{
+		depth:     0,
+		exclude:   listFlag{},
+		gitignore: false,
+		hidden:    false,
+		sizes:     false,
+		lines:     false,
+	}
+}
+
+// newTreeFlagSet returns the flag set used to parse the arguments of a tree
+// command into `args`
+func newTreeFlagSet(args *treeArgs) *flag.FlagSet {
+	treeFlag := flag.NewFlagSet("treeFlag", flag.ContinueOnError)
+	treeFlag.SetOutput(io.Discard)
+	treeFlag.IntVar(&args.depth, "depth", 0,
+		"Number of directory levels listed, every level when 0")
+	treeFlag.Var(&args.exclude, "exclude",
+		"Leave out the files and directories matching a pattern, for example __pycache__ or *.pyc")
+	treeFlag.BoolVar(&args.gitignore, "gitignore", false,
+		"Leave out the files ignored by the .gitignore files of the directory")
+	treeFlag.BoolVar(&args.hidden, "hidden", false,
+		"List the files and directories whose names start with a dot")
+	treeFlag.BoolVar(&args.sizes, "sizes", false,
+		"Show the size of every file")
+	treeFlag.BoolVar(&args.lines, "lines", false,
+		"Show the number of lines of every file")
+
+	return treeFlag
+}
+
+// treeEntry is a file or directory of a tree listing
+type treeEntry struct {
+	prefix string // the branches drawn before the name
+	name   string
+	link   string // target of a symbolic link, empty for other files
+	broken bool   // the link points to nothing
+	dir    bool
+	size   int64
+	lines  int
+}
+
+// ignoreRule is a pattern of a .gitignore file
+type ignoreRule struct {
+	base     string   // directory of the .gitignore file, relative to the root
+	elements []string // elements of the pattern, matched from base when anchored
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+// treeSnippet lists the files of the directory of a tree command in the
+// style of the tree program
+func (c Cinj) treeSnippet(cmd CinjCommand) (Snippet, error) {
+	tArgs := newTreeArgs()
+	treeFlags := newTreeFlagSet(tArgs)
+	if err := treeFlags.Parse(cmd.Args); err != nil {
+		return Snippet{}, err
+	}
+	if treeFlags.NArg() > 0 {
+		return Snippet{}, fmt.Errorf("Unexpected argument %s, the tree command takes a single directory",
+			treeFlags.Arg(0))
+	}
+	if tArgs.depth < 0 {
+		return Snippet{}, errors.New("The depth argument must be 0 or greater")
+	}
+	for _, pattern := range tArgs.exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Snippet{}, fmt.Errorf("Invalid exclude pattern %s: %w", pattern, err)
+		}
+	}
+
+	if cmd.revision(0) != "" {
+		return Snippet{}, errors.New("Can not list a directory at a git revision")
+	}
+	root := cmd.Filepath
+	info, err := os.Stat(root)
+	if err != nil {
+		return Snippet{}, err
+	}
+	if !info.IsDir() {
+		return Snippet{}, fmt.Errorf("Can not list %s, it is not a directory", filepath.Base(root))
+	}
+
+	entries := []treeEntry{}
+	dirs, files := 0, 0
+	var walk func(dir string, rel string, prefix string, level int, rules []ignoreRule) error
+	walk = func(dir string, rel string, prefix string, level int, rules []ignoreRule) error {
+		if tArgs.gitignore {
+			more, err := readGitignore(filepath.Join(dir, ".gitignore"), rel)
+			if err != nil {
+				return err
+			}
+			rules = append(rules[:len(rules):len(rules)], more...)
+		}
+
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		sort.Slice(children, func(i, j int) bool {
+			a, b := strings.ToLower(children[i].Name()), strings.ToLower(children[j].Name())
+			if a != b {
+				return a < b
+			}
+			return children[i].Name() < children[j].Name()
+		})
+
+		kept := children[:0:0]
+		for _, child := range children {
+			name := child.Name()
+			childRel := path.Join(rel, name)
+			switch {
+			case name == ".git" || (!tArgs.hidden && strings.HasPrefix(name, ".")):
+			case excluded(tArgs.exclude, name, childRel):
+			case tArgs.gitignore && ignored(rules, childRel, child.IsDir()):
+			default:
+				kept = append(kept, child)
+			}
+		}
+
+		for i, child := range kept {
+			branch, indent := "├── ", "│   "
+			if i == len(kept)-1 {
+				branch, indent = "└── ", "    "
+			}
+			entry := treeEntry{prefix: prefix + branch, name: child.Name(), dir: child.IsDir()}
+			childPath := filepath.Join(dir, child.Name())
+
+			// a link is listed with its target and counted as what it points
+			// to, without following a link to a directory, as tree does
+			if child.Type()&fs.ModeSymlink != 0 {
+				entry.link, err = os.Readlink(childPath)
+				if err != nil {
+					return err
+				}
+				info, err := os.Stat(childPath)
+				entry.broken = err != nil
+				entry.dir = !entry.broken && info.IsDir()
+			}
+
+			if entry.dir {
+				dirs++
+				entries = append(entries, entry)
+				if entry.link == "" && (tArgs.depth == 0 || level < tArgs.depth) {
+					if err := walk(childPath, path.Join(rel, child.Name()), prefix+indent, level+1, rules); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			files++
+			if (tArgs.sizes || tArgs.lines) && !entry.broken {
+				content, err := os.ReadFile(childPath)
+				if err != nil {
+					return err
+				}
+				entry.size = int64(len(content))
+				entry.lines = countLines(content)
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	}
+	if err := walk(root, "", "", 1, nil); err != nil {
+		return Snippet{}, err
+	}
+
+	name := filepath.Base(root)
+	if rel, err := filepath.Rel(filepath.Dir(c.Filepath), root); err == nil && filepath.IsLocal(rel) {
+		name = filepath.ToSlash(rel)
+	}
+	content := name + "\n" + treeLines(entries, tArgs.sizes, tArgs.lines) +
+		"\n" + treeSummary(dirs, files) + "\n"
+	return Snippet{Content: content, Language: Plain, Name: filepath.Base(root)}, nil
+}
+
+// treeLines writes the entries of a tree listing, with the sizes and line
+// counts of the files in columns after the names when they are asked for
+func treeLines(entries []treeEntry, sizes bool, lines bool) string {
+	width, linesWidth := 0, 0
+	for _, e := range entries {
+		width = max(width, len([]rune(e.prefix+e.name+e.linkSuffix())))
+		linesWidth = max(linesWidth, len(fmt.Sprint(e.lines)))
+	}
+
+	var sb strings.Builder
+	for _, e := range entries {
+		text := e.prefix + e.name + e.linkSuffix()
+		if e.dir {
+			text += "/"
+		}
+		if !e.dir && !e.broken && (sizes || lines) {
+			columns := []string{}
+			if sizes {
+				columns = append(columns, fmt.Sprintf("%5s", humanSize(e.size)))
+			}
+			if lines {
+				word := "lines"
+				if e.lines == 1 {
+					word = "line"
+				}
+				columns = append(columns, fmt.Sprintf("%*d %s", linesWidth, e.lines, word))
+			}
+			text += strings.Repeat(" ", width-len([]rune(text))+2) + strings.Join(columns, "  ")
+		}
+		sb.WriteString(text + "\n")
+	}
+	return sb.String()
+}
+
+// linkSuffix returns the arrow and target written after the name of a
+// symbolic link, such as ` -> ../shared`
+func (e treeEntry) linkSuffix() string {
+	if e.link == "" {
+		return ""
+	}
+	return " -> " + e.link
+}
+
+// treeSummary writes the counts of directories and files of a tree listing
+func treeSummary(dirs int, files int) string {
+	dirWord, fileWord := "directories", "files"
+	if dirs == 1 {
+		dirWord = "directory"
+	}
+	if files == 1 {
+		fileWord = "file"
+	}
+	return fmt.Sprintf("%d %s, %d %s", dirs, dirWord, files, fileWord)
+}
+
+// humanSize writes a size in bytes the way tree -h does, such as 512 or 1.2K
+func humanSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprint(size)
+	}
+	value := float64(size)
+	for _, unit := range []string{"K", "M", "G", "T"} {
+		value /= 1024
+		if value < 1024 || unit == "T" {
+			if value < 10 {
+				return fmt.Sprintf("%.1f%s", value, unit)
+			}
+			return fmt.Sprintf("%.0f%s", value, unit)
+		}
+	}
+	return fmt.Sprint(size)
+}
+
+// countLines returns the number of lines of a file, counting a last line
+// without a newline
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		lines++
+	}
+	return lines
+}
+
+// excluded reports whether a file matches one of the --exclude patterns.
+// Patterns with a slash are matched against the path from the root of the
+// listing, others against the name
+func excluded(patterns []string, name string, rel string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+			pattern = strings.Trim(pattern, "/")
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readGitignore reads the rules of a .gitignore file in the directory `base`,
+// relative to the root of the listing. A missing file has no rules
+func readGitignore(file string, base string) ([]ignoreRule, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []ignoreRule{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// a pattern with a slash before its end is anchored to the directory
+		// of the .gitignore file, others match at any depth
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rule.elements = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// ignored reports whether the file at `rel` from the root of the listing is
+// ignored by the rules, where the last matching rule wins
+func ignored(rules []ignoreRule, rel string, dir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.dirOnly && !dir {
+			continue
+		}
+		inBase := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			inBase = strings.TrimPrefix(rel, rule.base+"/")
+		}
+
+		var ok bool
+		if rule.anchored {
+			ok, _ = matchElements(rule.elements, strings.Split(inBase, "/"))
+		} else {
+			ok, _ = path.Match(rule.elements[0], path.Base(rel))
+		}
+		if ok {
+			result = !rule.negate
+		}
+	}
+	return result
+}
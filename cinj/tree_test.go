@@ -0,0 +1,78 @@
+package cinj
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTreeCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/app.py":                  "import lib\n\nlib.run()\n",
+		"src/lib/__init__.py":         "",
+		"src/lib/util.py":             strings.Repeat("x = 1\n", 12),
+		"src/lib/deep/more.py":        "y = 2",
+		"src/__pycache__/app.cpython": "bytes",
+		"src/.env":                    "SECRET=1\n",
+		"src/build/out.txt":           "built\n",
+		"src/keep.log":                "kept\n",
+		"src/debug.log":               "ignored\n",
+		"src/.gitignore":              "build/\n*.log\n!keep.log\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	links := map[string]string{
+		"src/lib/alias.py": "util.py",
+		"src/lib/shared":   "deep",
+		"src/lib/gone":     "missing.py",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+
+	c := Cinj{Filepath: filepath.Join(dir, "report.cinj")}
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		{`cinj{tree ./src --depth=2 --exclude="__pycache__" --gitignore}`,
+			"src\n├── app.py\n├── keep.log\n└── lib/\n    ├── __init__.py\n    ├── alias.py -> util.py\n" +
+				"    ├── deep/\n    ├── gone -> missing.py\n    ├── shared -> deep/\n    └── util.py\n\n" +
+				"3 directories, 6 files\n"},
+		{`cinj{tree ./src/lib --lines --sizes}`,
+			"src/lib\n├── __init__.py              0   0 lines\n├── alias.py -> util.py     72  12 lines\n" +
+				"├── deep/\n│   └── more.py              5   1 line\n├── gone -> missing.py\n" +
+				"├── shared -> deep/\n└── util.py                 72  12 lines\n\n2 directories, 5 files\n"},
+	}
+
+	for i, tt := range tests {
+		cmd, err := c.getCinjCommand(tt.directive)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got, err := c.getSnippet(cmd, *newRenderArgs())
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if got.Content != tt.expected {
+			t.Fatalf("tests[%d] - expected\n%s\ngot\n%s", i, tt.expected, got.Content)
+		}
+	}
+
+	cmd, _ := c.getCinjCommand("cinj{tree ./src/app.py}")
+	if _, err := c.getSnippet(cmd, *newRenderArgs()); err == nil {
+		t.Fatal("expected an error for a tree of a file")
+	}
+}
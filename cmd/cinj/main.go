@@ -1,13 +1,11 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	cinj "github.com/TheDavo/cinj/cinj"
 )
@@ -22,6 +20,13 @@ with tools such as 'pandoc' and 'weasyprint' for PDF generation.`
 func main() {
 	var cinj cinj.Cinj
 	var newname string
+	var configPath string
+	var captions bool
+	var flavor string
+	var outputFormat string
+	var redact bool
+	var checkSecrets bool
+	var offline bool
 
 	flag.StringVar(
 		&newname,
@@ -30,6 +35,55 @@ func main() {
 		"New name for output file, not including extension,\n\tfor example --newname new_report_name",
 	)
 
+	flag.StringVar(
+		&configPath,
+		"config",
+		"",
+		"Path to a config file, defaults to cinj.json next to the input file\n\tif there is one",
+	)
+
+	flag.BoolVar(
+		&captions,
+		"captions",
+		false,
+		"Write a caption with every snippet, such as\n\tListing 3: Client.fetch — src/client.py, lines 40–72",
+	)
+
+	flag.StringVar(
+		&flavor,
+		"flavor",
+		"",
+		"Renderer the output is written for: pandoc, mkdocs, vitepress or docusaurus,\n\tsets how highlighted lines are marked",
+	)
+
+	flag.StringVar(
+		&outputFormat,
+		"output-format",
+		"",
+		"Format of the new file: markdown, asciidoc, rst, latex, latex-minted, org or\n\thtml, defaults to the format of the input file, such as asciidoc for .cinj.adoc",
+	)
+
+	flag.BoolVar(
+		&redact,
+		"redact",
+		false,
+		"Replace the secrets found by the built-in detectors, such as AWS keys,\n\tbearer tokens and private keys, with <REDACTED> even when the config\n\tturns them off",
+	)
+
+	flag.BoolVar(
+		&checkSecrets,
+		"check-secrets",
+		false,
+		"Fail if a snippet has a secret that was not redacted",
+	)
+
+	flag.BoolVar(
+		&offline,
+		"offline",
+		false,
+		"Read files given as URLs from the cache only, without fetching them",
+	)
+
 	flag.Usage = func() {
 		w := flag.CommandLine.Output()
 
@@ -51,18 +105,45 @@ func main() {
 		log.Fatal(err)
 	}
 
-	_, fileNameNoExt, err := getExtension(absFp)
+	format, fileNameNoExt, err := getFormat(absFp)
 
 	if err != nil {
 		log.Fatal(err.Error())
 		os.Exit(1)
 	}
+	if outputFormat != "" {
+		format = outputFormat
+	}
+	extension, err := getFormatExtension(format)
+	if err != nil {
+		log.Fatal(err)
+	}
 	cinj.Filepath = absFp
+	cinj.Format = format
 
 	if newname == "" {
-		cinj.Newname = fileNameNoExt + ".md"
+		cinj.Newname = fileNameNoExt + extension
 	} else {
-		cinj.Newname = filepath.Join(filepath.Dir(absFp), newname+".md")
+		cinj.Newname = filepath.Join(filepath.Dir(absFp), newname+extension)
+	}
+
+	cinj.Config, err = loadConfig(configPath, filepath.Dir(absFp))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if captions {
+		cinj.Config.Captions.Enabled = true
+	}
+	if flavor != "" {
+		cinj.Config.Flavor = flavor
+	}
+	if redact {
+		builtin := true
+		cinj.Config.Redaction.Builtin = &builtin
+	}
+	cinj.CheckSecrets = checkSecrets
+	if offline {
+		cinj.Config.HTTP.Offline = true
 	}
 
 	err = cinj.Run()
@@ -72,25 +153,25 @@ func main() {
 	fmt.Println("Newly Cinj'd filename:", cinj.Newname)
 }
 
-// getExtension is a helper function that returns the correct Cinj-allowed
-// extensions or an error
-// The allowed extensions are ".cinj.md" or ".cinj"
-// This function returns ".cinj.md" or ".cinj" depending on extension
-func getExtension(fn string) (string, string, error) {
-	fileNoCinj, foundCinj := strings.CutSuffix(fn, ".cinj")
-	fileNoCinjMd, foundCinjMd := strings.CutSuffix(fn, ".cinj.md")
-
-	if !foundCinj && !foundCinjMd {
-		return "", "", errors.New("No appropriate file extension found")
-	}
-
-	if foundCinj {
-		return ".cinj", fileNoCinj, nil
+// loadConfig reads the config file given with the config flag, or the
+// cinj.json file in the directory of the input file if there is one
+func loadConfig(configPath string, dir string) (cinj.Config, error) {
+	if configPath != "" {
+		return cinj.LoadConfig(configPath)
 	}
+	return cinj.FindConfig(dir)
+}
 
-	if foundCinjMd {
-		return ".cinj.md", fileNoCinjMd, nil
-	}
+// getFormat returns the output format of the input file, found from its
+// extension, and the file name without the extension.
+// The allowed extensions are ".cinj", ".cinj.md", ".cinj.adoc", ".cinj.rst",
+// ".cinj.tex" and ".cinj.org"
+func getFormat(fn string) (string, string, error) {
+	return cinj.FormatForPath(fn)
+}
 
-	return "", "", errors.New("No appropriate file extension found")
+// getFormatExtension returns the extension of the new file for an output
+// format
+func getFormatExtension(format string) (string, error) {
+	return cinj.FormatExtension(format)
 }
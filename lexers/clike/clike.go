@@ -0,0 +1,411 @@
+// Package clike lexes and parses the structure of languages that delimit
+// blocks with braces, such as Go, JavaScript, C and Rust. The parser does not
+// know the full grammar of any language, it finds declarations such as
+// functions, classes and types by looking at the tokens before each block.
+package clike
+
+import (
+	"slices"
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+)
+
+const (
+	IDENT   = "IDENT"
+	NUMBER  = "NUMBER"
+	STRING  = "STRING"
+	COMMENT = "COMMENT"
+	PUNCT   = "PUNCT"
+	EOF     = "EOF"
+)
+
+// Language describes the lexical rules and declaration kinds of a language
+type Language struct {
+	Name         string   // name used for code blocks, such as "go"
+	Extensions   []string // file extensions, such as ".go"
+	LineComment  string
+	BlockComment [2]string
+	// Keywords are the reserved words of the language, used to highlight
+	// code
+	Keywords []string
+	// RawQuote is a quote that starts a string without escapes, such as the
+	// backtick in Go, or a template string in JavaScript
+	RawQuote byte
+	// Regex is set for languages with regular expression literals
+	Regex bool
+	// Lifetimes is set for languages where a single quote can start a
+	// lifetime instead of a character, such as Rust
+	Lifetimes bool
+	// Verbatim is set for languages with @"..." strings, such as C#
+	Verbatim bool
+	// NewlineTerminates is set for languages where a line break can end a
+	// statement instead of a semicolon, such as Go and JavaScript
+	NewlineTerminates bool
+	// classify names the declaration, if any, made by the tokens before a
+	// block. It returns an empty kind for blocks that are not declarations
+	classify func(header []lex.Token, parent *Node) (kind string, name string)
+}
+
+// Languages supported by the package, see kinds.go for their declarations
+var Languages = []*Language{golang, javascript, typescript, java, c, cpp, csharp, rust}
+
+// ForExtension returns the language for a file extension
+func ForExtension(ext string) (*Language, bool) {
+	ext = strings.ToLower(ext)
+	for _, language := range Languages {
+		for _, e := range language.Extensions {
+			if e == ext {
+				return language, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ForName returns the language with a code block name, such as "go"
+func ForName(name string) (*Language, bool) {
+	for _, language := range Languages {
+		if language.Name == name {
+			return language, true
+		}
+	}
+	return nil, false
+}
+
+// IsKeyword reports whether a word is a keyword of the language
+func (language *Language) IsKeyword(word string) bool {
+	return slices.Contains(language.Keywords, word)
+}
+
+// Lexer splits the input of a brace-delimited language into tokens
+type Lexer struct {
+	language *Language
+	input    string
+	position int
+	line     int
+	column   int
+	depth    int
+	tokens   []lex.Token
+}
+
+var _ lex.Lexer = (*Lexer)(nil)
+
+func NewLexer(input string, language *Language) *Lexer {
+	return &Lexer{
+		language: language,
+		input:    input,
+		line:     1,
+		column:   1,
+		depth:    1,
+	}
+}
+
+// Lex tokenizes the whole input of the lexer
+func (l *Lexer) Lex() {
+	for l.position < len(l.input) {
+		l.nextToken()
+	}
+	l.tokens = append(l.tokens, lex.Token{
+		Type:          EOF,
+		Line:          l.line,
+		Column:        l.column,
+		Depth:         l.depth,
+		StartPosition: len(l.input),
+		EndPosition:   len(l.input),
+	})
+}
+
+// Tokens returns the tokens found by Lex
+func (l Lexer) Tokens() []lex.Token {
+	return l.tokens
+}
+
+// MatchKeyword returns the type of an identifier token. Keywords are not
+// told apart from other identifiers, that is left to the declaration kinds
+func (l Lexer) MatchKeyword(t lex.Token) lex.TokenType {
+	return IDENT
+}
+
+func (l *Lexer) nextToken() {
+	ch := l.input[l.position]
+	if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' {
+		l.advance(1)
+		return
+	}
+
+	tok := lex.Token{
+		Line:          l.line,
+		Column:        l.column,
+		Depth:         l.depth,
+		StartPosition: l.position,
+	}
+	rest := l.input[l.position:]
+	language := l.language
+
+	switch {
+	case language.LineComment != "" && strings.HasPrefix(rest, language.LineComment):
+		tok.Type = COMMENT
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		l.advance(end)
+	case language.BlockComment[0] != "" && strings.HasPrefix(rest, language.BlockComment[0]):
+		tok.Type = COMMENT
+		end := strings.Index(rest[len(language.BlockComment[0]):], language.BlockComment[1])
+		if end < 0 {
+			l.advance(len(rest))
+		} else {
+			l.advance(len(language.BlockComment[0]) + end + len(language.BlockComment[1]))
+		}
+	case language.Verbatim && strings.HasPrefix(rest, `@"`):
+		tok.Type = STRING
+		l.advance(1)
+		l.verbatimString()
+	case language.Lifetimes && ch == 'r' && isRawRustString(rest):
+		tok.Type = STRING
+		l.rawRustString()
+	case ch == '"':
+		tok.Type = STRING
+		l.quotedString('"')
+	case ch == '\'':
+		if language.Lifetimes && !isCharLiteral(rest) {
+			tok.Type = PUNCT
+			l.advance(1)
+		} else {
+			tok.Type = STRING
+			l.quotedString('\'')
+		}
+	case language.RawQuote != 0 && ch == language.RawQuote:
+		tok.Type = STRING
+		l.rawString()
+	case language.Regex && ch == '/' && l.regexAllowed():
+		tok.Type = STRING
+		l.regexLiteral()
+	case isLetter(ch):
+		tok.Type = IDENT
+		l.advance(1)
+		for l.position < len(l.input) &&
+			(isLetter(l.input[l.position]) || isDigit(l.input[l.position])) {
+			l.advance(1)
+		}
+	case isDigit(ch):
+		tok.Type = NUMBER
+		l.advance(1)
+		for l.position < len(l.input) && (isLetter(l.input[l.position]) ||
+			isDigit(l.input[l.position]) || l.input[l.position] == '.') {
+			l.advance(1)
+		}
+	default:
+		tok.Type = PUNCT
+		for _, op := range []string{"=>", "::", "->", ":="} {
+			if strings.HasPrefix(rest, op) {
+				l.advance(len(op) - 1)
+				break
+			}
+		}
+		l.advance(1)
+		switch ch {
+		case '{':
+			l.depth++
+		case '}':
+			l.depth--
+		}
+	}
+
+	tok.EndPosition = l.position
+	tok.Literal = l.input[tok.StartPosition:tok.EndPosition]
+	l.tokens = append(l.tokens, tok)
+}
+
+// advance moves the lexer n bytes forward, keeping track of lines and
+// columns
+func (l *Lexer) advance(n int) {
+	for i := 0; i < n && l.position < len(l.input); i++ {
+		if l.input[l.position] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.position++
+	}
+}
+
+// quotedString reads a string or character literal with backslash escapes
+func (l *Lexer) quotedString(quote byte) {
+	l.advance(1)
+	for l.position < len(l.input) {
+		ch := l.input[l.position]
+		switch {
+		case ch == '\\':
+			l.advance(2)
+		case ch == quote:
+			l.advance(1)
+			return
+		case ch == '\n':
+			// unterminated, stop at the end of the line
+			return
+		default:
+			l.advance(1)
+		}
+	}
+}
+
+// rawString reads a string that ends at the next raw quote. Template strings
+// in JavaScript can hold ${...} expressions, which can hold strings too
+func (l *Lexer) rawString() {
+	quote := l.input[l.position]
+	l.advance(1)
+	for l.position < len(l.input) {
+		rest := l.input[l.position:]
+		switch {
+		case l.language.Regex && rest[0] == '\\':
+			l.advance(2)
+		case l.language.Regex && strings.HasPrefix(rest, "${"):
+			l.advance(2)
+			l.skipBalanced()
+		case rest[0] == quote:
+			l.advance(1)
+			return
+		default:
+			l.advance(1)
+		}
+	}
+}
+
+// skipBalanced reads until the brace that closes an already opened brace,
+// skipping over strings inside
+func (l *Lexer) skipBalanced() {
+	depth := 1
+	for l.position < len(l.input) && depth > 0 {
+		switch ch := l.input[l.position]; ch {
+		case '{':
+			depth++
+			l.advance(1)
+		case '}':
+			depth--
+			l.advance(1)
+		case '"', '\'':
+			l.quotedString(ch)
+		case l.language.RawQuote:
+			l.rawString()
+		default:
+			l.advance(1)
+		}
+	}
+}
+
+// verbatimString reads a C# @"..." string, where "" is an escaped quote
+func (l *Lexer) verbatimString() {
+	l.advance(1)
+	for l.position < len(l.input) {
+		if l.input[l.position] == '"' {
+			if strings.HasPrefix(l.input[l.position:], `""`) {
+				l.advance(2)
+				continue
+			}
+			l.advance(1)
+			return
+		}
+		l.advance(1)
+	}
+}
+
+// isRawRustString reports whether the input starts a Rust raw string such
+// as r"..." or r#"..."#
+func isRawRustString(rest string) bool {
+	i := 1
+	for i < len(rest) && rest[i] == '#' {
+		i++
+	}
+	return i < len(rest) && rest[i] == '"' && (i > 1 || rest[1] == '"')
+}
+
+// rawRustString reads a Rust raw string, ending at a quote followed by the
+// same number of # as the opening quote
+func (l *Lexer) rawRustString() {
+	rest := l.input[l.position:]
+	hashes := 0
+	for rest[1+hashes] == '#' {
+		hashes++
+	}
+	closing := "\"" + strings.Repeat("#", hashes)
+	end := strings.Index(rest[2+hashes:], closing)
+	if end < 0 {
+		l.advance(len(rest))
+		return
+	}
+	l.advance(2 + hashes + end + len(closing))
+}
+
+// isCharLiteral tells a Rust character literal such as 'a' or '\n' apart
+// from a lifetime such as 'a
+func isCharLiteral(rest string) bool {
+	if len(rest) >= 2 && rest[1] == '\\' {
+		return true
+	}
+	return len(rest) >= 3 && rest[2] == '\''
+}
+
+// regexAllowed reports whether a '/' starts a regular expression literal,
+// which is when it can not be a division
+func (l *Lexer) regexAllowed() bool {
+	rest := l.input[l.position:]
+	if strings.HasPrefix(rest, "//") || strings.HasPrefix(rest, "/*") {
+		return false
+	}
+
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		prev := l.tokens[i]
+		switch prev.Type {
+		case COMMENT:
+			continue
+		case IDENT:
+			return prev.Literal == "return" || prev.Literal == "typeof" ||
+				prev.Literal == "case"
+		case NUMBER, STRING:
+			return false
+		case PUNCT:
+			return prev.Literal != ")" && prev.Literal != "]" && prev.Literal != "}"
+		}
+	}
+	return true
+}
+
+// regexLiteral reads a regular expression literal and its flags
+func (l *Lexer) regexLiteral() {
+	l.advance(1)
+	inClass := false
+	for l.position < len(l.input) {
+		ch := l.input[l.position]
+		switch {
+		case ch == '\\':
+			l.advance(2)
+			continue
+		case ch == '[':
+			inClass = true
+		case ch == ']':
+			inClass = false
+		case ch == '/' && !inClass:
+			l.advance(1)
+			for l.position < len(l.input) && isLetter(l.input[l.position]) {
+				l.advance(1)
+			}
+			return
+		case ch == '\n':
+			return
+		}
+		l.advance(1)
+	}
+}
+
+func isLetter(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,267 @@
+package clike
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// declarations lists the kind and name of every node under root, one per
+// line and indented by depth
+func declarations(root *Node) string {
+	var sb strings.Builder
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		for _, child := range n.Children {
+			line := fmt.Sprintf("%s%s %s", strings.Repeat("  ", depth), child.Kind, child.Name)
+			sb.WriteString(strings.TrimRight(line, " ") + "\n")
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	return sb.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		language *Language
+		input    string
+		expected string
+	}{
+		{golang, `package main
+
+// Server { is not a block in a comment
+type Server struct {
+	name string
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if s.name == "}" {
+		return nil
+	}
+	handler := func(w http.ResponseWriter) {
+		fmt.Println(` + "`{`" + `)
+	}
+	return nil
+}
+
+func main() {
+	for _, x := range []int{1, 2} {
+		_ = x
+	}
+}
+`, `type_declaration Server
+method_declaration Start
+  func_literal handler
+function_declaration main
+`},
+		{typescript, `interface Props {
+	title: string
+}
+
+export class App extends Component<Props> {
+	state = { open: false }
+
+	render(): string {
+		const re = /[}]/g
+		if (this.state.open) {
+			return ` + "`${this.props.title}}`" + `
+		}
+		return ""
+	}
+
+	static create() {
+		return new App()
+	}
+}
+
+const add = (a: number, b: number) => {
+	return a + b
+}
+
+function helper() {
+	items.forEach(function (item) {
+		console.log(item)
+	})
+}
+`, `interface_declaration Props
+class_declaration App
+  method_definition render
+  method_definition create
+arrow_function add
+function_declaration helper
+  function_expression
+`},
+		{typescript, `export function helper(a: string, b: number = 1): void {
+	return
+}
+
+export const format = function (value: string): string {
+	return value
+}
+
+const handler: Handler = (e: Event): void => {
+	log(e)
+}
+
+export default function <T>(items: T[]): T[] {
+	return items
+}
+`, `function_declaration helper
+function_expression format
+arrow_function handler
+function_expression
+`},
+		{java, `package demo;
+
+public class Greeter<T> implements Runnable {
+	private final String name;
+
+	public Greeter(String name) {
+		this.name = name;
+	}
+
+	@Override
+	public void run() {
+		Runnable r = () -> {
+			System.out.println("{");
+		};
+	}
+
+	enum Mood { HAPPY, SAD }
+}
+`, `class_declaration Greeter
+  constructor_declaration Greeter
+  method_declaration run
+  enum_declaration Mood
+`},
+		{c, `#include <stdio.h>
+
+struct point {
+	int x, y;
+};
+
+static int add(int a, int b)
+{
+	if (a > b) {
+		return a;
+	}
+	return a + b;
+}
+
+int main(void) {
+	char *s = "}";
+	return add(1, 2);
+}
+`, `struct_specifier point
+function_definition add
+function_definition main
+`},
+		{rust, `struct Point<'a> {
+    name: &'a str,
+}
+
+impl<'a> fmt::Display for Point<'a> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        let c = '}';
+        write!(f, r#"{"}"#)
+    }
+}
+
+fn main() {
+    if true {
+        println!("{}", 1);
+    }
+}
+`, `struct_item Point
+impl_item Point
+  function_item fmt
+function_item main
+`},
+	}
+
+	for _, tt := range tests {
+		root := Parse(tt.input, tt.language)
+		got := declarations(root)
+		if got != tt.expected {
+			t.Errorf("%s - wrong declarations. expected=\n%s\ngot=\n%s",
+				tt.language.Name, tt.expected, got)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	input := `class App {
+	render() {
+		return 1
+	}
+}
+
+class Other {
+	render() {
+		return 2
+	}
+}
+`
+	root := Parse(input, javascript)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`(method_definition name: "render")`, []string{"render", "render"}},
+		{`(class_declaration name: "Other" (method_definition name: "render"))`,
+			[]string{"Other"}},
+		{`(class_declaration (method_definition name: "missing"))`, []string{}},
+		{`(_ name: "App")`, []string{"App"}},
+	}
+
+	for _, tt := range tests {
+		pattern, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s - unexpected error %v", tt.query, err)
+		}
+		matches := pattern.Matches(root)
+		names := []string{}
+		for _, m := range matches {
+			names = append(names, m.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s - wrong matches. expected=%v, got=%v", tt.query, tt.expected, names)
+		}
+	}
+
+	matches := mustMatch(t, root, `(class_declaration name: "Other" (method_definition))`)
+	if text := input[matches[0].Start:matches[0].End]; !strings.HasPrefix(text, "class Other {") ||
+		!strings.HasSuffix(text, "}\n}") {
+		t.Errorf("wrong span for Other, got=%q", text)
+	}
+	if matches[0].StartLine != 7 || matches[0].EndLine != 11 {
+		t.Errorf("wrong lines for Other. expected=7-11, got=%d-%d",
+			matches[0].StartLine, matches[0].EndLine)
+	}
+
+	typed := Parse("function helper(a: string): void {\n\treturn\n}\n", typescript)
+	if matches := mustMatch(t, typed, "(function_declaration)"); matches[0].Name != "helper" {
+		t.Errorf("expected helper, got=%q", matches[0].Name)
+	}
+
+	for _, query := range []string{"method_definition", "(method_definition", `(x name: render)`, "(x) y"} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("%s - expected an error", query)
+		}
+	}
+}
+
+func mustMatch(t *testing.T, root *Node, query string) []*Node {
+	t.Helper()
+	pattern, err := ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := pattern.Matches(root)
+	if len(matches) == 0 {
+		t.Fatalf("%s - no matches", query)
+	}
+	return matches
+}
@@ -0,0 +1,476 @@
+package clike
+
+import (
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+)
+
+// controlKeywords start blocks that are never declarations
+var controlKeywords = map[string]bool{
+	"if": true, "else": true, "for": true, "foreach": true, "while": true,
+	"do": true, "switch": true, "try": true, "catch": true, "finally": true,
+	"with": true, "return": true, "case": true, "default": true, "loop": true,
+	"match": true, "unsafe": true, "select": true, "go": true, "defer": true,
+	"using": true, "lock": true, "fixed": true, "synchronized": true,
+	"sizeof": true, "typeof": true, "new": true, "await": true, "yield": true,
+}
+
+var golang = &Language{
+	Name:         "go",
+	Extensions:   []string{".go"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`break case chan const continue default defer else fallthrough for func go
+	goto if import interface map package range return select struct switch type
+	var true false nil iota`),
+	RawQuote:          '`',
+	NewlineTerminates: true,
+	classify:          classifyGo,
+}
+
+var javascript = &Language{
+	Name:         "javascript",
+	Extensions:   []string{".js", ".mjs", ".cjs", ".jsx"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`async await break case catch class const continue debugger default delete
+	do else export extends false finally for function get if import in
+	instanceof let new null of return set static super switch this throw true
+	try typeof undefined var void while with yield`),
+	RawQuote:          '`',
+	Regex:             true,
+	NewlineTerminates: true,
+	classify:          classifyJavascript,
+}
+
+var typescript = &Language{
+	Name:         "typescript",
+	Extensions:   []string{".ts", ".mts", ".cts", ".tsx"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`abstract any as async await boolean break case catch class const continue
+	debugger declare default delete do else enum export extends false finally
+	for function get if implements import in instanceof interface keyof let
+	namespace never new null number of private protected public readonly
+	return set static string super switch this throw true try type typeof
+	undefined unknown var void while with yield`),
+	RawQuote:          '`',
+	Regex:             true,
+	NewlineTerminates: true,
+	classify:          classifyJavascript,
+}
+
+var java = &Language{
+	Name:         "java",
+	Extensions:   []string{".java"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`abstract assert boolean break byte case catch char class const continue
+	default do double else enum extends false final finally float for if
+	implements import instanceof int interface long native new null package
+	private protected public record return short static super switch
+	synchronized this throw throws transient true try var void volatile while`),
+	classify: classifyJava,
+}
+
+var csharp = &Language{
+	Name:         "csharp",
+	Extensions:   []string{".cs"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`abstract as async await base bool break byte case catch char class const
+	continue decimal default delegate do double else enum event explicit extern
+	false finally fixed float for foreach get if implicit in int interface
+	internal is lock long namespace new null object operator out override
+	params private protected public readonly record ref return sealed set
+	short static string struct switch this throw true try typeof uint ulong
+	using var virtual void while`),
+	Verbatim: true,
+	classify: classifyJava,
+}
+
+var c = &Language{
+	Name:         "c",
+	Extensions:   []string{".c", ".h"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`auto bool break case char const continue default do double else enum extern
+	false float for goto if inline int long register return short signed sizeof
+	static struct switch true typedef union unsigned void volatile while NULL`),
+	classify: classifyC,
+}
+
+var cpp = &Language{
+	Name:         "cpp",
+	Extensions:   []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`auto bool break case catch char class const constexpr continue default
+	delete do double else enum explicit extern false float for friend goto if
+	inline int long namespace new noexcept nullptr operator override private
+	protected public register return short signed sizeof static struct switch
+	template this throw true try typedef typename union unsigned using virtual
+	void volatile while`),
+	classify: classifyC,
+}
+
+var rust = &Language{
+	Name:         "rust",
+	Extensions:   []string{".rs"},
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+	Keywords: strings.Fields(`as async await break const continue crate dyn else enum extern false fn for
+	if impl in let loop match mod move mut pub ref return self Self static
+	struct super trait true type unsafe use where while`),
+	Lifetimes: true,
+	classify:  classifyRust,
+}
+
+// classifyGo finds function_declaration, method_declaration, func_literal
+// and type_declaration blocks
+func classifyGo(header []lex.Token, parent *Node) (string, string) {
+	if len(header) < 2 {
+		return "", ""
+	}
+
+	switch header[0].Literal {
+	case "func":
+		if header[1].Literal == "(" {
+			if end := closingParen(header, 1); end+1 < len(header) &&
+				header[end+1].Type == IDENT {
+				return "method_declaration", header[end+1].Literal
+			}
+			return "func_literal", ""
+		}
+		return "function_declaration", header[1].Literal
+	case "type":
+		return "type_declaration", header[1].Literal
+	}
+
+	if i := indexOf(header, "func"); i > 0 {
+		if assign := indexOf(header, ":="); assign > 0 && assign < i {
+			return "func_literal", header[assign-1].Literal
+		}
+		if assign := indexOf(header, "="); assign > 0 && assign < i {
+			return "func_literal", header[assign-1].Literal
+		}
+		return "func_literal", ""
+	}
+
+	return "", ""
+}
+
+// classifyJavascript finds class_declaration, function_declaration,
+// method_definition, arrow_function and function_expression blocks, and the
+// interface_declaration, enum_declaration and internal_module blocks of
+// TypeScript
+func classifyJavascript(header []lex.Token, parent *Node) (string, string) {
+	if len(header) == 0 || controlKeywords[header[0].Literal] {
+		return "", ""
+	}
+
+	for _, keyword := range []struct{ keyword, kind string }{
+		{"class", "class_declaration"},
+		{"interface", "interface_declaration"},
+		{"enum", "enum_declaration"},
+		{"namespace", "internal_module"},
+	} {
+		if i := indexTopLevel(header, keyword.keyword); i >= 0 {
+			if name := identAt(header, i+1); name != "" && name != "extends" &&
+				name != "implements" {
+				return keyword.kind, name
+			}
+			return keyword.kind, assignedName(header)
+		}
+	}
+
+	if i := indexTopLevel(header, "function"); i >= 0 {
+		name := identAt(header, i+1)
+		if i+1 < len(header) && header[i+1].Literal == "*" {
+			name = identAt(header, i+2) // generator functions, function*
+		}
+		// an = or : after the keyword is in the parameters or the return type
+		// of a declaration, only one before it assigns the function
+		if before := header[:i]; indexTopLevel(before, "=") >= 0 ||
+			indexTopLevel(before, ":") >= 0 {
+			return "function_expression", assignedName(before)
+		}
+		if name == "" {
+			return "function_expression", ""
+		}
+		return "function_declaration", name
+	}
+
+	if i := indexTopLevel(header, "=>"); i >= 0 {
+		return "arrow_function", assignedName(header[:i])
+	}
+
+	if parent != nil && parent.Kind == "class_declaration" &&
+		indexTopLevel(header, "=") < 0 {
+		if name := identBeforeParen(header); name != "" {
+			return "method_definition", name
+		}
+	}
+
+	return "", ""
+}
+
+// classifyJava finds class_declaration, interface_declaration,
+// enum_declaration, record_declaration, method_declaration and
+// constructor_declaration blocks, and for C# the struct_declaration,
+// namespace_declaration and property_declaration blocks
+func classifyJava(header []lex.Token, parent *Node) (string, string) {
+	if len(header) == 0 || controlKeywords[header[0].Literal] {
+		return "", ""
+	}
+
+	for _, keyword := range []struct{ keyword, kind string }{
+		{"class", "class_declaration"},
+		{"interface", "interface_declaration"},
+		{"enum", "enum_declaration"},
+		{"record", "record_declaration"},
+		{"struct", "struct_declaration"},
+		{"namespace", "namespace_declaration"},
+	} {
+		if i := indexTopLevel(header, keyword.keyword); i >= 0 {
+			return keyword.kind, dottedName(header, i+1)
+		}
+	}
+
+	if parent == nil || !isTypeDeclaration(parent.Kind) ||
+		indexTopLevel(header, "=") >= 0 || indexTopLevel(header, "->") >= 0 ||
+		indexTopLevel(header, "=>") >= 0 {
+		return "", ""
+	}
+
+	if name := identBeforeParen(header); name != "" {
+		if name == parent.Name {
+			return "constructor_declaration", name
+		}
+		return "method_declaration", name
+	}
+
+	if last := header[len(header)-1]; last.Type == IDENT && len(header) > 1 {
+		return "property_declaration", last.Literal
+	}
+
+	return "", ""
+}
+
+// classifyC finds function_definition, struct_specifier, union_specifier,
+// enum_specifier blocks, and for C++ the class_specifier and
+// namespace_definition blocks
+func classifyC(header []lex.Token, parent *Node) (string, string) {
+	if len(header) == 0 || controlKeywords[header[0].Literal] {
+		return "", ""
+	}
+
+	if indexTopLevel(header, "(") < 0 {
+		for _, keyword := range []struct{ keyword, kind string }{
+			{"struct", "struct_specifier"},
+			{"union", "union_specifier"},
+			{"enum", "enum_specifier"},
+			{"class", "class_specifier"},
+			{"namespace", "namespace_definition"},
+		} {
+			if i := indexTopLevel(header, keyword.keyword); i >= 0 {
+				name := identAt(header, i+1)
+				if name == "class" || name == "struct" {
+					name = identAt(header, i+2) // enum class Name
+				}
+				return keyword.kind, name
+			}
+		}
+		return "", ""
+	}
+
+	if indexTopLevel(header, "=") >= 0 {
+		return "", ""
+	}
+
+	if name := identBeforeParen(header); name != "" {
+		return "function_definition", name
+	}
+
+	return "", ""
+}
+
+// classifyRust finds function_item, struct_item, enum_item, union_item,
+// trait_item, impl_item, mod_item and macro_definition blocks
+func classifyRust(header []lex.Token, parent *Node) (string, string) {
+	if len(header) == 0 || controlKeywords[header[0].Literal] {
+		return "", ""
+	}
+
+	if i := indexTopLevel(header, "macro_rules"); i >= 0 {
+		return "macro_definition", identAt(header, i+2)
+	}
+
+	for _, keyword := range []struct{ keyword, kind string }{
+		{"fn", "function_item"},
+		{"struct", "struct_item"},
+		{"enum", "enum_item"},
+		{"union", "union_item"},
+		{"trait", "trait_item"},
+		{"mod", "mod_item"},
+	} {
+		if i := indexTopLevel(header, keyword.keyword); i >= 0 {
+			return keyword.kind, identAt(header, i+1)
+		}
+	}
+
+	if i := indexTopLevel(header, "impl"); i >= 0 {
+		if f := indexTopLevel(header, "for"); f > i {
+			return "impl_item", identAt(header, f+1)
+		}
+		return "impl_item", identAt(header, skipGenerics(header, i+1))
+	}
+
+	return "", ""
+}
+
+// isTypeDeclaration reports whether methods can be declared inside of a
+// block of the kind
+func isTypeDeclaration(kind string) bool {
+	switch kind {
+	case "class_declaration", "interface_declaration", "enum_declaration",
+		"record_declaration", "struct_declaration":
+		return true
+	}
+	return false
+}
+
+// indexOf returns the index of the first token with the literal, or -1
+func indexOf(header []lex.Token, literal string) int {
+	for i, tok := range header {
+		if tok.Literal == literal {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexTopLevel returns the index of the first token with the literal that
+// is not inside of brackets, or -1
+func indexTopLevel(header []lex.Token, literal string) int {
+	depth := 0
+	for i, tok := range header {
+		if depth == 0 && tok.Literal == literal && tok.Type != STRING {
+			return i
+		}
+		switch tok.Literal {
+		case "(", "[", "<":
+			if tok.Literal != "<" || literal != "(" {
+				depth++
+			}
+		case ")", "]", ">":
+			if depth > 0 && (tok.Literal != ">" || literal != "(") {
+				depth--
+			}
+		}
+	}
+	return -1
+}
+
+// identAt returns the literal of the token at i if it is an identifier
+func identAt(header []lex.Token, i int) string {
+	if i >= 0 && i < len(header) && header[i].Type == IDENT {
+		return header[i].Literal
+	}
+	return ""
+}
+
+// dottedName returns the name starting at i, joining parts separated by dots
+// such as a C# namespace
+func dottedName(header []lex.Token, i int) string {
+	name := identAt(header, i)
+	for name != "" && i+2 < len(header) && header[i+1].Literal == "." &&
+		header[i+2].Type == IDENT {
+		name += "." + header[i+2].Literal
+		i += 2
+	}
+	return name
+}
+
+// assignedName returns the name a declaration is assigned to, such as f in
+// `const f = () => {` or `const f: Handler = () => {`, or an empty string
+func assignedName(header []lex.Token) string {
+	first := -1
+	for _, op := range []string{"=", ":"} {
+		if i := indexTopLevel(header, op); i > 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return identAt(header, first-1)
+}
+
+// identBeforeParen returns the identifier before the first opening
+// parenthesis, skipping type parameters such as `<T>`
+func identBeforeParen(header []lex.Token) string {
+	paren := indexTopLevel(header, "(")
+	if paren <= 0 {
+		return ""
+	}
+
+	i := paren - 1
+	if header[i].Literal == ">" {
+		depth := 0
+		for ; i >= 0; i-- {
+			if header[i].Literal == ">" {
+				depth++
+			} else if header[i].Literal == "<" {
+				depth--
+				if depth == 0 {
+					i--
+					break
+				}
+			}
+		}
+	}
+
+	name := identAt(header, i)
+	if controlKeywords[name] {
+		return ""
+	}
+	return name
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// `open`
+func closingParen(header []lex.Token, open int) int {
+	depth := 0
+	for i := open; i < len(header); i++ {
+		switch header[i].Literal {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(header)
+}
+
+// skipGenerics returns the index after the type parameters starting at i,
+// or i when there are none
+func skipGenerics(header []lex.Token, i int) int {
+	if i >= len(header) || header[i].Literal != "<" {
+		return i
+	}
+	depth := 0
+	for ; i < len(header); i++ {
+		switch header[i].Literal {
+		case "<":
+			depth++
+		case ">":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,170 @@
+package clike
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pattern matches a node by its kind and, optionally, its name. Child
+// patterns must each match a node somewhere below the matched node, such as
+// `(class_declaration name: "App" (method_definition name: "render"))`
+type Pattern struct {
+	Kind     string // "_" matches any kind
+	Name     string
+	HasName  bool
+	Children []*Pattern
+}
+
+// ParseQuery parses a query written in the S-expression style of
+// tree-sitter queries. Only node kinds, `name:` fields and nested patterns
+// are supported
+func ParseQuery(query string) (*Pattern, error) {
+	q := queryParser{input: query}
+	pattern, err := q.pattern()
+	if err != nil {
+		return nil, err
+	}
+	q.skipSpace()
+	if q.pos < len(q.input) {
+		return nil, fmt.Errorf("Unexpected %q at position %d of query",
+			q.input[q.pos:], q.pos)
+	}
+	return pattern, nil
+}
+
+type queryParser struct {
+	input string
+	pos   int
+}
+
+func (q *queryParser) skipSpace() {
+	for q.pos < len(q.input) && strings.ContainsRune(" \t\r\n", rune(q.input[q.pos])) {
+		q.pos++
+	}
+}
+
+func (q *queryParser) pattern() (*Pattern, error) {
+	q.skipSpace()
+	if q.pos >= len(q.input) || q.input[q.pos] != '(' {
+		return nil, fmt.Errorf("Expected ( at position %d of query", q.pos)
+	}
+	q.pos++
+
+	q.skipSpace()
+	kind := q.word()
+	if kind == "" {
+		return nil, fmt.Errorf("Expected a node kind at position %d of query",
+			q.pos)
+	}
+	pattern := &Pattern{Kind: kind}
+
+	for {
+		q.skipSpace()
+		if q.pos >= len(q.input) {
+			return nil, fmt.Errorf("Missing ) at the end of query")
+		}
+
+		switch q.input[q.pos] {
+		case ')':
+			q.pos++
+			return pattern, nil
+		case '(':
+			child, err := q.pattern()
+			if err != nil {
+				return nil, err
+			}
+			pattern.Children = append(pattern.Children, child)
+		default:
+			field := q.word()
+			if field != "name" || q.pos >= len(q.input) || q.input[q.pos] != ':' {
+				return nil, fmt.Errorf("Expected name: at position %d of query",
+					q.pos-len(field))
+			}
+			q.pos++
+			q.skipSpace()
+			name, err := q.quoted()
+			if err != nil {
+				return nil, err
+			}
+			pattern.Name = name
+			pattern.HasName = true
+		}
+	}
+}
+
+// word reads a node kind or field name
+func (q *queryParser) word() string {
+	start := q.pos
+	for q.pos < len(q.input) {
+		ch := q.input[q.pos]
+		if ch != '_' && !isLetter(ch) && !isDigit(ch) {
+			break
+		}
+		q.pos++
+	}
+	return q.input[start:q.pos]
+}
+
+// quoted reads a double quoted string with Go escapes
+func (q *queryParser) quoted() (string, error) {
+	if q.pos >= len(q.input) || q.input[q.pos] != '"' {
+		return "", fmt.Errorf("Expected a quoted name at position %d of query",
+			q.pos)
+	}
+	for end := q.pos + 1; end < len(q.input); end++ {
+		switch q.input[end] {
+		case '\\':
+			end++
+		case '"':
+			value, err := strconv.Unquote(q.input[q.pos : end+1])
+			if err != nil {
+				return "", fmt.Errorf("Invalid name %s in query", q.input[q.pos:end+1])
+			}
+			q.pos = end + 1
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("Unterminated name at position %d of query", q.pos)
+}
+
+// Matches returns the nodes under root matched by the pattern, in the order
+// they appear in the source
+func (p *Pattern) Matches(root *Node) []*Node {
+	matches := []*Node{}
+	root.Walk(func(n *Node) {
+		if n != root && p.match(n) {
+			matches = append(matches, n)
+		}
+	})
+	return matches
+}
+
+func (p *Pattern) match(n *Node) bool {
+	if p.Kind != "_" && p.Kind != n.Kind {
+		return false
+	}
+	if p.HasName && p.Name != n.Name {
+		return false
+	}
+	for _, child := range p.Children {
+		if len(child.Matches(n)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Kinds returns the declaration kinds found in the tree, used to suggest
+// kinds when a query does not match anything
+func Kinds(root *Node) []string {
+	seen := map[string]bool{}
+	kinds := []string{}
+	root.Walk(func(n *Node) {
+		if n != root && !seen[n.Kind] {
+			seen[n.Kind] = true
+			kinds = append(kinds, n.Kind)
+		}
+	})
+	return kinds
+}
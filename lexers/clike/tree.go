@@ -0,0 +1,185 @@
+package clike
+
+import (
+	lex "github.com/TheDavo/cinj/lexers"
+)
+
+// SourceFile is the kind of the root node returned by Parse
+const SourceFile = "source_file"
+
+// Node is a declaration found in the input, such as a function or a class.
+// The kinds are named after the tree-sitter grammar of each language
+type Node struct {
+	Kind      string
+	Name      string
+	Start     int // position of the first token of the declaration
+	End       int // position just after the closing brace
+	StartLine int
+	EndLine   int
+	BodyLine  int // line of the opening brace of the body
+	Children  []*Node
+}
+
+// Walk calls fn for the node and every node below it, parents before
+// their children
+func (n *Node) Walk(fn func(*Node)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// Parse returns the declarations of the input as a tree. Blocks that are not
+// declarations, such as the body of an if statement, do not get a node, the
+// declarations inside of them belong to the closest declaration around them
+func Parse(input string, language *Language) *Node {
+	l := NewLexer(input, language)
+	l.Lex()
+
+	root := &Node{
+		Kind:      SourceFile,
+		Start:     0,
+		End:       len(input),
+		StartLine: 1,
+		EndLine:   l.line,
+	}
+	p := parser{tokens: l.tokens, language: language, input: input}
+	p.parseBlock(root, root)
+
+	return root
+}
+
+type parser struct {
+	tokens   []lex.Token
+	language *Language
+	input    string
+	pos      int
+}
+
+// parseBlock reads tokens until the brace closing the current block, adding
+// the declarations found to `parent`. `direct` is the declaration whose body
+// is the current block, or nil when the block is not a declaration
+func (p *parser) parseBlock(parent *Node, direct *Node) lex.Token {
+	lo := p.pos
+	parenDepth := 0
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		if tok.Type == EOF {
+			return tok
+		}
+		if tok.Type != PUNCT {
+			p.pos++
+			continue
+		}
+
+		switch tok.Literal {
+		case "(", "[":
+			parenDepth++
+		case ")", "]":
+			if parenDepth > 0 {
+				parenDepth--
+			}
+		case "}":
+			p.pos++
+			return tok
+		case "{":
+			header := p.header(lo, p.pos, parenDepth > 0)
+			kind, name := p.language.classify(header, direct)
+			start := tok
+			if len(header) > 0 {
+				start = header[0]
+			}
+			p.pos++
+
+			if kind == "" {
+				p.parseBlock(parent, nil)
+				continue
+			}
+
+			node := &Node{
+				Kind:      kind,
+				Name:      name,
+				Start:     start.StartPosition,
+				StartLine: start.Line,
+				BodyLine:  tok.Line,
+			}
+			closing := p.parseBlock(node, node)
+			node.End = closing.EndPosition
+			node.EndLine = closing.Line
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		p.pos++
+	}
+
+	return p.tokens[len(p.tokens)-1]
+}
+
+// header returns the tokens before the opening brace at `brace` that belong
+// to the same statement, without comments. The search stops at the start of
+// the block `lo`, at a statement boundary, or at an unclosed bracket
+func (p *parser) header(lo int, brace int, inParens bool) []lex.Token {
+	depth := 0
+	start := brace
+
+	for i := brace - 1; i >= lo; i-- {
+		tok := p.tokens[i]
+		if tok.Type == COMMENT {
+			continue
+		}
+
+		if depth == 0 && p.language.NewlineTerminates && endsStatement(tok) &&
+			p.nextLine(i, start, brace) {
+			break
+		}
+
+		if tok.Type == PUNCT {
+			switch tok.Literal {
+			case ")", "]":
+				depth++
+			case "(", "[":
+				depth--
+			case ";", "{", "}":
+				if depth == 0 {
+					depth = -1
+				}
+			case ",":
+				if depth == 0 && inParens {
+					depth = -1
+				}
+			}
+		}
+		if depth < 0 {
+			break
+		}
+		start = i
+	}
+
+	header := []lex.Token{}
+	for i := start; i < brace; i++ {
+		if p.tokens[i].Type != COMMENT {
+			header = append(header, p.tokens[i])
+		}
+	}
+	return header
+}
+
+// nextLine reports whether the first header token found so far, at `start`,
+// is on a later line than the token at i. A brace on its own line does not
+// count, as no statement ends before an opening brace
+func (p *parser) nextLine(i int, start int, brace int) bool {
+	return start < brace && p.tokens[start].Line > p.tokens[i].Line
+}
+
+// endsStatement reports whether a line ending with the token ends the
+// statement in languages without mandatory semicolons
+func endsStatement(tok lex.Token) bool {
+	switch tok.Type {
+	case IDENT, NUMBER, STRING:
+		return true
+	case PUNCT:
+		return tok.Literal == ")" || tok.Literal == "]" || tok.Literal == "}"
+	}
+	return false
+}
@@ -1,8 +1,11 @@
 package lexers
 
+// Lexer is implemented by the language lexers used by the extractors
 type Lexer interface {
+	// Lex tokenizes the whole input of the lexer
 	Lex()
-	NewLexer() *Lexer
+	// Tokens returns the tokens found by Lex
+	Tokens() []Token
 	MatchKeyword(Token) TokenType
 }
 
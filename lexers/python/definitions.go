@@ -0,0 +1,264 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+)
+
+// Scope is a class or function block that encloses a definition
+type Scope struct {
+	Kind   lex.TokenType // CLASS or FUNCTION
+	Name   string
+	Line   int
+	Header string // the definition statement, including its indentation
+}
+
+// Definition is a class or function definition found in the lexer input
+type Definition struct {
+	Kind       lex.TokenType // CLASS or FUNCTION
+	Name       string
+	Line       int
+	Indent     string   // leading whitespace of the definition line
+	Decorators []string // decorator names without the '@' or call arguments
+	Scopes     []Scope  // enclosing blocks, outermost first
+	Text       string   // decorators and the block of the definition
+	// StartLine is the line of the first line of Text and EndLine the last
+	// line that is not blank
+	StartLine int
+	EndLine   int
+	// BodyLine is the first line of the block after the definition
+	// statement, or 0 when the block is on the same line as the statement
+	BodyLine int
+}
+
+// Class returns the name of the closest class enclosing the definition, or
+// an empty string for module level definitions
+func (d Definition) Class() string {
+	for i := len(d.Scopes) - 1; i >= 0; i-- {
+		if d.Scopes[i].Kind == CLASS {
+			return d.Scopes[i].Name
+		}
+	}
+	return ""
+}
+
+// HasDecorator reports whether the definition is decorated with `name`.
+// Dotted decorators match on their last component, so `setter` matches
+// `@value.setter` and `overload` matches `@typing.overload`
+func (d Definition) HasDecorator(name string) bool {
+	name = strings.TrimPrefix(name, "@")
+	for _, decorator := range d.Decorators {
+		if decorator == name || strings.HasSuffix(decorator, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Location describes where the definition is, used for error messages
+func (d Definition) Location() string {
+	if class := d.Class(); class != "" && d.Kind != CLASS {
+		return fmt.Sprintf("line %d (in class %s)", d.Line, class)
+	}
+	return fmt.Sprintf("line %d", d.Line)
+}
+
+// FindClasses returns every definition of the class `className` in the
+// order they appear in the lexer input
+func (pl *PythonLexer) FindClasses(className string) ([]Definition, error) {
+	defs := pl.findDefinitions(CLASS, className)
+	if len(defs) == 0 {
+		return defs, errors.New("Could not find class")
+	}
+	return defs, nil
+}
+
+// FindFunctions returns every definition of the function `functionName`.
+// When `className` is not empty only the functions enclosed by that class
+// are returned
+func (pl *PythonLexer) FindFunctions(functionName string,
+	className string,
+) ([]Definition, error) {
+	all := pl.findDefinitions(FUNCTION, functionName)
+	defs := []Definition{}
+	for _, def := range all {
+		if className == "" || def.inClass(className) {
+			defs = append(defs, def)
+		}
+	}
+
+	if len(defs) == 0 {
+		if className != "" {
+			return defs, fmt.Errorf("Could not find function %s in class %s",
+				functionName, className)
+		}
+		return defs, fmt.Errorf("Could not find function %s", functionName)
+	}
+	return defs, nil
+}
+
+// Functions returns every function definition of the lexer input, methods
+// and nested functions included, in the order they appear
+func (pl *PythonLexer) Functions() []Definition {
+	defs := []Definition{}
+	seen := map[string]bool{}
+	for idx := 1; idx < len(pl.tokens); idx++ {
+		name := pl.tokens[idx].Literal
+		if pl.tokens[idx].Type != IDENT || pl.tokens[idx-1].Type != FUNCTION || seen[name] {
+			continue
+		}
+		seen[name] = true
+		defs = append(defs, pl.findDefinitions(FUNCTION, name)...)
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Line < defs[j].Line })
+	return defs
+}
+
+// inClass reports whether any class enclosing the definition is `className`
+func (d Definition) inClass(className string) bool {
+	for _, scope := range d.Scopes {
+		if scope.Kind == CLASS && scope.Name == className {
+			return true
+		}
+	}
+	return false
+}
+
+// findDefinitions returns the definitions of keyword type `tt` whose
+// identifier is `ident`
+func (pl PythonLexer) findDefinitions(tt lex.TokenType,
+	ident string,
+) []Definition {
+	defs := []Definition{}
+	for idx := 1; idx < len(pl.tokens); idx++ {
+		if pl.tokens[idx].Type != IDENT || pl.tokens[idx].Literal != ident ||
+			pl.tokens[idx-1].Type != tt {
+			continue
+		}
+
+		keyword := pl.tokens[idx-1]
+		// A block that runs to the end of the input has no token after it
+		// to end the range, so it ends with the input instead
+		_, end, err := pl.findBlockRangePosFromToken(keyword, idx-1)
+		if err != nil || end >= pl.tokens[len(pl.tokens)-1].StartPosition {
+			end = len(pl.input)
+		}
+
+		decorators := pl.findDecoratorsAboveToken(keyword)
+		var text string
+		if tt == FUNCTION && idx >= 2 {
+			// idx-2 here to grab the correct spacing, the tokenizer skips
+			// indentation so the start position of idx-1 is after it
+			text = pl.input[pl.tokens[idx-2].StartPosition:end]
+			if decorators != "" {
+				// the decorator lines end with a newline already
+				text = decorators + strings.TrimPrefix(text, "\n")
+			}
+		} else {
+			text = decorators + pl.input[keyword.StartPosition:end]
+		}
+		// the lines of the text before the keyword are decorators and the
+		// end of the previous line
+		before := text[:len(text)-(end-keyword.StartPosition)]
+		startLine := keyword.Line - strings.Count(before, "\n")
+
+		defs = append(defs, Definition{
+			Kind:       tt,
+			Name:       ident,
+			Line:       keyword.Line,
+			Indent:     pl.input[pl.lineStart(keyword.Line):keyword.StartPosition],
+			Decorators: decoratorNames(decorators),
+			Scopes:     pl.findScopes(idx - 1),
+			Text:       text,
+			StartLine:  startLine,
+			EndLine:    startLine + strings.Count(strings.TrimRight(text, " \t\r\n"), "\n"),
+			BodyLine:   pl.bodyLine(idx),
+		})
+	}
+
+	return defs
+}
+
+// bodyLine returns the first line of the block of the definition whose name
+// is the token at idx. The statement ends at the first colon outside of
+// parentheses, the block starts on the next line unless there is code after
+// the colon
+func (pl PythonLexer) bodyLine(idx int) int {
+	depth := 0
+	for i := idx + 1; i < len(pl.tokens); i++ {
+		tok := pl.tokens[i]
+		switch tok.Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			depth--
+		case COLON:
+			if depth > 0 {
+				continue
+			}
+			rest := pl.input[tok.StartPosition+len(":"):]
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				rest = rest[:end]
+			}
+			rest = strings.TrimSpace(rest)
+			if rest == "" || strings.HasPrefix(rest, "#") {
+				return tok.Line + 1
+			}
+			return 0
+		case EOF:
+			return 0
+		}
+	}
+	return 0
+}
+
+// findScopes walks back from the token at idx and returns the class and
+// function blocks that enclose it, outermost first
+func (pl PythonLexer) findScopes(idx int) []Scope {
+	scopes := []Scope{}
+	statements := pl.statementLines()
+	depth := pl.tokens[idx].Depth
+
+	for i := idx - 1; i >= 0 && depth > 1; i-- {
+		tok := pl.tokens[i]
+		if tok.Depth >= depth || tok.Type == NEWLINE || tok.Type == IGNORE {
+			continue
+		}
+		if i > 0 && pl.tokens[i-1].Type != NEWLINE || !statements[tok.Line] {
+			continue
+		}
+
+		depth = tok.Depth
+		if (tok.Type == CLASS || tok.Type == FUNCTION) && i+1 < len(pl.tokens) {
+			start := pl.lineStart(tok.Line)
+			scopes = append([]Scope{{
+				Kind:   tok.Type,
+				Name:   pl.tokens[i+1].Literal,
+				Line:   tok.Line,
+				Header: pl.input[start:pl.statementEnd(start)],
+			}}, scopes...)
+		}
+	}
+
+	return scopes
+}
+
+// decoratorNames returns the names used in a decorator block, dropping the
+// '@' and any call arguments
+func decoratorNames(decorators string) []string {
+	names := []string{}
+	for _, line := range strings.Split(decorators, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		name, _, _ := strings.Cut(line[1:], "(")
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
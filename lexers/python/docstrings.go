@@ -0,0 +1,125 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FindMainBlock returns the `if __name__ == "__main__":` block of the lexer
+// input
+func (pl *PythonLexer) FindMainBlock() (Statement, error) {
+	statements := pl.statementLines()
+	for idx, tok := range pl.tokens {
+		if tok.Literal != "if" || tok.Depth != 1 || !statements[tok.Line] ||
+			(idx > 0 && pl.tokens[idx-1].Type != NEWLINE) {
+			continue
+		}
+
+		start := pl.lineStart(tok.Line)
+		header := strings.Join(strings.Fields(pl.input[start:pl.statementEnd(start)]), "")
+		header = strings.ReplaceAll(header, "'", "\"")
+		if header != `if__name__=="__main__":` && header != `if"__main__"==__name__:` {
+			continue
+		}
+
+		_, end, err := pl.findBlockRangePosFromToken(tok, idx)
+		if err != nil || end >= pl.tokens[len(pl.tokens)-1].StartPosition {
+			end = len(pl.input)
+		}
+		return Statement{Text: pl.input[start:end], Line: tok.Line}, nil
+	}
+
+	return Statement{}, errors.New("Could not find an if __name__ == \"__main__\" block")
+}
+
+// GetMainBlock returns the `if __name__ == "__main__":` block of the lexer
+// input
+func (pl *PythonLexer) GetMainBlock() (string, error) {
+	block, err := pl.FindMainBlock()
+	return block.Text, err
+}
+
+// Docstring returns the docstring of a definition without its quotes, or an
+// error if the definition does not start with a string
+func (pl *PythonLexer) Docstring(def Definition) (string, error) {
+	pos := pl.statementEnd(pl.lineStart(def.Line))
+
+	// Skip blank lines and comments between the header and the body
+	for pos < len(pl.input) {
+		switch pl.input[pos] {
+		case ' ', '\t', '\r', '\n':
+			pos++
+			continue
+		case '#':
+			pos = pl.statementEnd(pos)
+			continue
+		}
+		break
+	}
+
+	for pos < len(pl.input) && strings.ContainsRune("rRuU", rune(pl.input[pos])) {
+		pos++
+	}
+	if pos >= len(pl.input) || (pl.input[pos] != '"' && pl.input[pos] != '\'') {
+		return "", fmt.Errorf("%s does not have a docstring", def.Name)
+	}
+
+	quote := pl.input[pos : pos+1]
+	if strings.HasPrefix(pl.input[pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	body := pl.input[pos+len(quote):]
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(body[i:], quote) {
+			return body[:i], nil
+		}
+	}
+
+	return "", fmt.Errorf("The docstring of %s is not terminated", def.Name)
+}
+
+// Doctest returns the interactive examples, the `>>>` and `...` lines and
+// their expected output, of a docstring. Examples that are apart in the
+// docstring are separated by a blank line
+func Doctest(docstring string) string {
+	examples := []string{}
+	var example []string
+	indent := ""
+	inExample := false
+
+	flush := func() {
+		if len(example) > 0 {
+			examples = append(examples, strings.Join(example, "\n")+"\n")
+		}
+		example = nil
+		inExample = false
+	}
+
+	for _, line := range strings.Split(docstring, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(line, " \t")
+
+		switch {
+		case strings.HasPrefix(trimmed, ">>>"):
+			if !inExample {
+				indent = line[:len(line)-len(trimmed)]
+			}
+			inExample = true
+			example = append(example, strings.TrimPrefix(line, indent))
+		case !inExample:
+			continue
+		case trimmed == "":
+			flush()
+		default:
+			example = append(example, strings.TrimPrefix(line, indent))
+		}
+	}
+	flush()
+
+	return strings.Join(examples, "\n")
+}
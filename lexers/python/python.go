@@ -24,6 +24,7 @@ const (
 	DECORATOR  = "@"
 	IMPORT     = "IMPORT"
 	FROM       = "FROM"
+	ASSIGN     = "ASSIGN"
 )
 
 var keywords = map[string]lex.TokenType{
@@ -88,6 +89,8 @@ func NewLexer(input string, indentSize int) *PythonLexer {
 	}
 }
 
+var _ lex.Lexer = (*PythonLexer)(nil)
+
 func (pl *PythonLexer) Lex() {
 	pl.readChar()
 	for !pl.isAtEnd() {
@@ -95,6 +98,11 @@ func (pl *PythonLexer) Lex() {
 	}
 }
 
+// Tokens returns the tokens found by Lex
+func (pl PythonLexer) Tokens() []lex.Token {
+	return pl.tokens
+}
+
 func (pl *PythonLexer) nextToken() lex.Token {
 	var tok lex.Token
 
@@ -147,6 +155,17 @@ func (pl *PythonLexer) nextToken() lex.Token {
 	case ')':
 		tok.Type = RPAREN
 		tok.Literal = ")"
+	case '=':
+		// Comparisons are not assignments, consume both characters so the
+		// second '=' is not lexed on its own
+		if pl.peekChar() == '=' {
+			pl.readChar()
+			tok.Type = IGNORE
+			tok.Literal = IGNORE
+		} else {
+			tok.Type = ASSIGN
+			tok.Literal = "="
+		}
 	case 0:
 		tok.Type = EOF
 		tok.Literal = ""
@@ -467,91 +486,52 @@ func (pl PythonLexer) findBlockRangePosFromToken(t lex.Token, idx int) (int, int
 	return 0, 0, errors.New("Cannot find block range for the token")
 }
 
-// GetClass returns a string corresponding to a class block in the text input
-// of the lexer
+// GetClass returns a string corresponding to the first class block named
+// `className` in the text input of the lexer
 func (pl *PythonLexer) GetClass(className string) (string, error) {
-	_, foundTokenLocs, err := pl.findTokens(IDENT, className)
+	defs, err := pl.FindClasses(className)
 	if err != nil {
 		return "", err
 	}
 
-	for _, idx := range foundTokenLocs {
-		if pl.tokens[idx-1].Type == CLASS {
-			decorators := pl.findDecoratorsAboveToken(pl.tokens[idx-1])
-			_, end, err := pl.findBlockRangePos(IDENT, className)
-			if err != nil {
-				return "", err
-			}
-			return fmt.Sprintf(
-				"%s%s",
-				decorators,
-				pl.input[pl.tokens[idx-1].StartPosition:end],
-			), nil
-		}
-	}
-
-	return "", errors.New("Could not find class")
+	return defs[0].Text, nil
 }
 
-// GetFunction returns a string corresponding to a function
-// block in the text input of the lexer
+// GetFunction returns a string corresponding to the first function
+// block named `functionName` in the text input of the lexer. When
+// `className` is given the function must be inside of that class, and the
+// class definition line is added above the function
 func (pl *PythonLexer) GetFunction(functionName string,
 	className string,
 ) (string, error) {
-	_, foundTokenLocs, err := pl.findTokens(IDENT, functionName)
+	defs, err := pl.FindFunctions(functionName, className)
 	if err != nil {
 		return "", err
 	}
 
-	keyClassLines, err := pl.findKeywordThenIdentifierLines(CLASS, className)
-	if err != nil {
-		return "", err
+	return pl.ClassSeparated(defs[0], className), nil
+}
+
+// ClassSeparated returns the text of a definition preceded by the definition
+// line of its enclosing class `className` and a `#----` separator line.
+// The text is returned unchanged when `className` is empty
+func (pl *PythonLexer) ClassSeparated(def Definition, className string) string {
+	if className == "" {
+		return def.Text
 	}
 
-	for _, idx := range foundTokenLocs {
-		// checks that the preceding token is a function
-		// to validate the current token is not a function call
-		// but a definition
-		if pl.tokens[idx-1].Type == FUNCTION {
-			_, end, err := pl.findBlockRangePosFromToken(pl.tokens[idx-1], idx-1)
+	for _, scope := range def.Scopes {
+		if scope.Kind == CLASS && scope.Name == className {
+			// offset since the line parameter is 1-indexed
+			parentLine, err := pl.getLine(scope.Line - 1)
 			if err != nil {
-				return "", err
-			}
-
-			decorators := pl.findDecoratorsAboveToken(pl.tokens[idx-1])
-
-			if className == "" {
-				return fmt.Sprintf(
-					"%s%s",
-					decorators,
-					pl.input[pl.tokens[idx-2].StartPosition:end],
-				), nil
-				// return pl.input[pl.tokens[idx-2].StartPosition:end], nil
-			}
-
-			// Add flavor text showing which class the function is in
-			for _, classLine := range keyClassLines {
-				if classLine < pl.tokens[idx].Line {
-					parentLine, err := pl.getLine(
-						classLine - 1,
-					) // offset since the line parameter is 1-indexed
-					if err != nil {
-						return "", err
-					}
-					function := fmt.Sprintf("%s#----%s%s",
-						// idx-2 here to grab the correct spacing
-						// idx-1 does not work as the tokenizer skips indentation
-						// and the start position of idx-1 is after
-						// the indentation
-						parentLine, decorators,
-						pl.input[pl.tokens[idx-2].StartPosition:end])
-					return function, nil
-
-				}
+				return def.Text
 			}
+			return fmt.Sprintf("%s#----%s", parentLine, def.Text)
 		}
 	}
-	return "", errors.New("Could not find class")
+
+	return def.Text
 }
 
 // findDecoratorsAboveToken is a helper function to find decorators that
@@ -2,6 +2,7 @@ package python
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	lex "github.com/TheDavo/cinj/lexers"
@@ -269,3 +270,321 @@ class Test2:
 		}
 	}
 }
+
+func TestGetImports(t *testing.T) {
+	input := `"""Module docstring"""
+import os
+import sys
+
+# third party
+from requests import (
+    Session,
+    adapters,
+)
+print(os.getcwd())
+import late
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	imports, err := l.GetImports()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `import os
+import sys
+
+# third party
+from requests import (
+    Session,
+    adapters,
+)
+import late
+`
+	if imports != expected {
+		t.Fatalf("Expected \n%s\nGot \n%s", expected, imports)
+	}
+}
+
+func TestGetVariable(t *testing.T) {
+	input := `DEFAULT_TIMEOUT = {
+    "connect": 5,
+    "read": (10,
+             20),
+}
+MAX_RETRIES: int = 3
+
+def f():
+    DEFAULT_TIMEOUT = 1
+    call(MAX_RETRIES=4)
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"DEFAULT_TIMEOUT", `DEFAULT_TIMEOUT = {
+    "connect": 5,
+    "read": (10,
+             20),
+}
+`},
+		{"MAX_RETRIES", "MAX_RETRIES: int = 3\n"},
+	}
+
+	for i, test := range tests {
+		got, err := l.GetVariable(test.name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if got != test.expected {
+			t.Fatalf("tests[%d]: expected \n%s\nGot \n%s", i, test.expected, got)
+		}
+	}
+
+	if _, err := l.GetVariable("connect"); err == nil {
+		t.Fatal("expected an error for a dict key, got none")
+	}
+}
+
+func TestGetAttribute(t *testing.T) {
+	input := `class Config:
+    retries: int = 3
+    hosts = [
+        "a",
+        "b",
+    ]
+    timeout: float
+
+    def __init__(self):
+        retries = 10
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	tests := []struct {
+		attribute string
+		expected  string
+	}{
+		{"retries", "class Config:\n#----\n    retries: int = 3\n"},
+		{"hosts", "class Config:\n#----\n    hosts = [\n        \"a\",\n        \"b\",\n    ]\n"},
+		{"timeout", "class Config:\n#----\n    timeout: float\n"},
+	}
+
+	for i, test := range tests {
+		got, err := l.GetAttribute(test.attribute, "Config")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if got != test.expected {
+			t.Fatalf("tests[%d]: expected \n%s\nGot \n%s", i, test.expected, got)
+		}
+	}
+}
+
+func TestFindFunctions(t *testing.T) {
+	input := `class Temp:
+    @property
+    def value(self):
+        return self._v
+
+    @value.setter
+    def value(self, v):
+        self._v = v
+
+if TYPE_CHECKING:
+    def value() -> int: ...
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	defs, err := l.FindFunctions("value", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		line      int
+		firstLine int // first line that is not blank, the decorators
+		endLine   int
+		class     string
+		decorator string
+	}{
+		{3, 2, 4, "Temp", "property"},
+		{7, 6, 8, "Temp", "setter"},
+		{11, 11, 11, "", ""},
+	}
+
+	if len(defs) != len(tests) {
+		t.Fatalf("expected %d definitions, got %d", len(tests), len(defs))
+	}
+
+	for i, test := range tests {
+		if defs[i].Line != test.line {
+			t.Fatalf("tests[%d]: expected line %d, got %d", i, test.line, defs[i].Line)
+		}
+		leading := len(defs[i].Text) - len(strings.TrimLeft(defs[i].Text, "\n"))
+		if first := defs[i].StartLine + leading; first != test.firstLine ||
+			defs[i].EndLine != test.endLine {
+			t.Fatalf("tests[%d]: expected lines %d-%d, got %d-%d", i, test.firstLine,
+				test.endLine, first, defs[i].EndLine)
+		}
+		if defs[i].Class() != test.class {
+			t.Fatalf("tests[%d]: expected class %q, got %q", i, test.class, defs[i].Class())
+		}
+		if test.decorator != "" && !defs[i].HasDecorator(test.decorator) {
+			t.Fatalf("tests[%d]: expected decorator %s, got %v", i, test.decorator,
+				defs[i].Decorators)
+		}
+	}
+
+	inClass, err := l.FindFunctions("value", "Temp")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(inClass) != 2 {
+		t.Fatalf("expected 2 definitions in class Temp, got %d", len(inClass))
+	}
+}
+
+func TestGetMainBlock(t *testing.T) {
+	input := `def main():
+    pass
+
+if __name__ == '__main__':
+    main()
+    print("done")
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	block, err := l.GetMainBlock()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := "if __name__ == '__main__':\n    main()\n    print(\"done\")\n"
+	if block != expected {
+		t.Fatalf("Expected \n%s\nGot \n%s", expected, block)
+	}
+}
+
+func TestDoctest(t *testing.T) {
+	input := `def add(a, b):
+    """Add two numbers.
+
+    >>> add(1, 2)
+    3
+    >>> add(
+    ...     2, 2)
+    4
+
+    Strings work too:
+
+    >>> add("a", "b")
+    'ab'
+    """
+    return a + b
+`
+
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	defs, err := l.FindFunctions("add", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	docstring, err := l.Docstring(defs[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `>>> add(1, 2)
+3
+>>> add(
+...     2, 2)
+4
+
+>>> add("a", "b")
+'ab'
+`
+	if got := Doctest(docstring); got != expected {
+		t.Fatalf("Expected \n%s\nGot \n%s", expected, got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	input := `@cache
+def f(x=1.5):  # comment
+    return rb'\'' + """a
+b""" if x else None
+`
+	expected := []struct {
+		tokenType string
+		literal   string
+	}{
+		{PUNCT, "@"}, {IDENT, "cache"}, {KEYWORD, "def"}, {IDENT, "f"},
+		{PUNCT, "("}, {IDENT, "x"}, {PUNCT, "="}, {NUMBER, "1.5"},
+		{PUNCT, ")"}, {PUNCT, ":"}, {COMMENT, "# comment"},
+		{KEYWORD, "return"}, {STRING, `rb'\''`}, {PUNCT, "+"},
+		{STRING, "\"\"\"a\nb\"\"\""}, {KEYWORD, "if"}, {IDENT, "x"},
+		{KEYWORD, "else"}, {KEYWORD, "None"},
+	}
+
+	tokens := Tokenize(input)
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, tt := range expected {
+		if string(tokens[i].Type) != tt.tokenType || tokens[i].Literal != tt.literal {
+			t.Fatalf("tokens[%d] - Expected %s %q, got %s %q", i, tt.tokenType,
+				tt.literal, tokens[i].Type, tokens[i].Literal)
+		}
+	}
+	if tokens[len(tokens)-1].Line != 4 {
+		t.Fatalf("Expected the last token on line 4, got %d", tokens[len(tokens)-1].Line)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	input := `class A:
+    def f(self,
+          x):  # note
+        return x
+
+    def g(self): return 1
+
+def h():
+    def inner(): pass
+`
+	l := NewLexer(input, 4)
+	l.Lex()
+
+	expected := []struct {
+		name     string
+		bodyLine int
+		scopes   int
+	}{
+		{"f", 4, 1}, {"g", 0, 1}, {"h", 9, 0}, {"inner", 0, 1},
+	}
+	defs := l.Functions()
+	if len(defs) != len(expected) {
+		t.Fatalf("Expected %d functions, got %d", len(expected), len(defs))
+	}
+	for i, tt := range expected {
+		if defs[i].Name != tt.name || defs[i].BodyLine != tt.bodyLine ||
+			len(defs[i].Scopes) != tt.scopes {
+			t.Fatalf("functions[%d] - Expected %s with body on line %d, got %s on line %d",
+				i, tt.name, tt.bodyLine, defs[i].Name, defs[i].BodyLine)
+		}
+	}
+}
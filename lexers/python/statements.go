@@ -0,0 +1,270 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// reservedWords are Python keywords that the lexer reports as identifiers.
+// They are needed to tell a statement such as `else:` apart from an
+// annotated assignment such as `retries: int`
+var reservedWords = map[string]bool{
+	"if": true, "elif": true, "else": true, "try": true, "except": true,
+	"finally": true, "while": true, "for": true, "with": true, "return": true,
+	"pass": true, "lambda": true, "match": true, "case": true, "async": true,
+	"global": true, "nonlocal": true, "del": true, "assert": true,
+	"raise": true, "yield": true, "break": true, "continue": true,
+}
+
+// statementEnd returns the position just after the logical line that starts
+// at pos. Brackets, strings, comments and backslash continuations are
+// followed so that a multi-line statement such as a dict literal is
+// returned whole
+func (pl PythonLexer) statementEnd(pos int) int {
+	depth := 0
+	var quote byte
+	triple := false
+
+	for i := pos; i < len(pl.input); i++ {
+		ch := pl.input[i]
+
+		if quote != 0 {
+			switch {
+			case ch == '\\':
+				i++
+			case ch == quote && !triple:
+				quote = 0
+			case ch == quote && triple && strings.HasPrefix(
+				pl.input[i:], strings.Repeat(string(quote), 3)):
+				quote = 0
+				i += 2
+			case ch == '\n' && !triple:
+				// unterminated string, end the statement here
+				return i + 1
+			}
+			continue
+		}
+
+		switch ch {
+		case '\'', '"':
+			quote = ch
+			triple = strings.HasPrefix(pl.input[i:], strings.Repeat(string(ch), 3))
+			if triple {
+				i += 2
+			}
+		case '#':
+			for i < len(pl.input) && pl.input[i] != '\n' {
+				i++
+			}
+			if i < len(pl.input) && depth == 0 {
+				return i + 1
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '\\':
+			if i+1 < len(pl.input) && pl.input[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(pl.input)
+}
+
+// statementLines returns the set of lines on which a logical statement
+// starts. Lines that continue a bracketed or backslash-continued statement
+// are not part of the set
+func (pl PythonLexer) statementLines() map[int]bool {
+	lines := map[int]bool{}
+	line := 1
+	for pos := 0; pos < len(pl.input); {
+		lines[line] = true
+		end := pl.statementEnd(pos)
+		line += strings.Count(pl.input[pos:end], "\n")
+		pos = end
+	}
+	return lines
+}
+
+// lineStart returns the position of the first character of a 1-indexed line,
+// including its indentation
+func (pl PythonLexer) lineStart(line int) int {
+	if line-1 < 0 || line-1 >= len(pl.lines) {
+		return 0
+	}
+	return pl.lines[line-1].StartPosition
+}
+
+// isAssignmentTarget reports whether the token at idx is the name on the
+// left-hand side of an assignment, annotated or not, at the start of a
+// logical statement
+func (pl PythonLexer) isAssignmentTarget(idx int, statements map[int]bool) bool {
+	tok := pl.tokens[idx]
+	if tok.Type != IDENT || reservedWords[tok.Literal] {
+		return false
+	}
+	if idx > 0 && pl.tokens[idx-1].Type != NEWLINE {
+		return false
+	}
+	if !statements[tok.Line] || idx+1 >= len(pl.tokens) {
+		return false
+	}
+
+	next := pl.tokens[idx+1].Type
+	return next == ASSIGN || next == COLON
+}
+
+// Statement is a piece of the lexer input along with the line it starts on
+type Statement struct {
+	Text string
+	Line int // line of the first line of Text
+}
+
+// FindImports returns the module level import statements of the lexer
+// input. Blank lines and comments between two imports are kept with the
+// import that follows them, so the grouping of the original file is
+// preserved
+func (pl *PythonLexer) FindImports() ([]Statement, error) {
+	statements := pl.statementLines()
+	imports := []Statement{}
+	lastEnd, lastLine := -1, 0
+
+	for i, tok := range pl.tokens {
+		if tok.Type != IMPORT && tok.Type != FROM {
+			continue
+		}
+		if tok.Depth != 1 || (i > 0 && pl.tokens[i-1].Type != NEWLINE) ||
+			!statements[tok.Line] {
+			continue
+		}
+
+		start, line := pl.lineStart(tok.Line), tok.Line
+		end := pl.statementEnd(start)
+		if lastEnd >= 0 && isBlankOrComment(pl.input[lastEnd:start]) {
+			start, line = lastEnd, lastLine
+		}
+		imports = append(imports, Statement{Text: pl.input[start:end], Line: line})
+		lastEnd = end
+		lastLine = tok.Line + strings.Count(pl.input[pl.lineStart(tok.Line):end], "\n")
+	}
+
+	if lastEnd < 0 {
+		return imports, errors.New("Could not find any imports")
+	}
+	return imports, nil
+}
+
+// GetImports returns the module level import statements of the lexer input.
+// Blank lines and comments between two imports are kept so the grouping of
+// the original file is preserved
+func (pl *PythonLexer) GetImports() (string, error) {
+	imports, err := pl.FindImports()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, statement := range imports {
+		sb.WriteString(statement.Text)
+	}
+	return sb.String(), nil
+}
+
+// FindVariable returns the module level assignment of the variable `name`,
+// including every line of a multi-line value such as a dict literal
+func (pl *PythonLexer) FindVariable(name string) (Statement, error) {
+	statements := pl.statementLines()
+	for i, tok := range pl.tokens {
+		if tok.Literal != name || tok.Depth != 1 {
+			continue
+		}
+		if pl.isAssignmentTarget(i, statements) {
+			start := pl.lineStart(tok.Line)
+			return Statement{Text: pl.input[start:pl.statementEnd(start)], Line: tok.Line}, nil
+		}
+	}
+
+	return Statement{}, fmt.Errorf("Could not find variable %s", name)
+}
+
+// GetVariable returns the module level assignment of the variable `name`,
+// including every line of a multi-line value such as a dict literal
+func (pl *PythonLexer) GetVariable(name string) (string, error) {
+	statement, err := pl.FindVariable(name)
+	return statement.Text, err
+}
+
+// FindAttribute returns the assignment of the class attribute `attribute`
+// inside of the class `className`, along with the class it is in
+func (pl *PythonLexer) FindAttribute(attribute string, className string) (Scope,
+	Statement, error,
+) {
+	statements := pl.statementLines()
+	for idx, tok := range pl.tokens {
+		if tok.Type != CLASS || idx+1 >= len(pl.tokens) ||
+			pl.tokens[idx+1].Literal != className {
+			continue
+		}
+
+		_, end, err := pl.findBlockRangePosFromToken(tok, idx)
+		if err != nil {
+			return Scope{}, Statement{}, err
+		}
+
+		for i := idx + 1; i < len(pl.tokens); i++ {
+			member := pl.tokens[i]
+			if member.StartPosition >= end {
+				break
+			}
+			if member.Literal != attribute || member.Depth != tok.Depth+1 {
+				continue
+			}
+			if pl.isAssignmentTarget(i, statements) {
+				classLine, err := pl.getLine(tok.Line - 1)
+				if err != nil {
+					return Scope{}, Statement{}, err
+				}
+				attrStart := pl.lineStart(member.Line)
+				class := Scope{Kind: CLASS, Name: className, Line: tok.Line, Header: classLine}
+				return class, Statement{
+					Text: pl.input[attrStart:pl.statementEnd(attrStart)],
+					Line: member.Line,
+				}, nil
+			}
+		}
+	}
+
+	return Scope{}, Statement{}, fmt.Errorf("Could not find attribute %s in class %s",
+		attribute, className)
+}
+
+// GetAttribute returns the assignment of the class attribute `attribute`
+// inside of the class `className`, preceded by the class definition line
+func (pl *PythonLexer) GetAttribute(attribute string, className string) (string,
+	error,
+) {
+	class, statement, err := pl.FindAttribute(attribute, className)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s#----\n%s", class.Header, statement.Text), nil
+}
+
+// isBlankOrComment reports whether every line of s is empty or a comment
+func isBlankOrComment(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
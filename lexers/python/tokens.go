@@ -0,0 +1,120 @@
+package python
+
+import (
+	"slices"
+	"strings"
+
+	lex "github.com/TheDavo/cinj/lexers"
+)
+
+// Token types found by Tokenize. The PythonLexer only finds the tokens that
+// make up the structure of a file, Tokenize also finds the strings, comments
+// and numbers in between
+const (
+	COMMENT = "COMMENT"
+	STRING  = "STRING"
+	NUMBER  = "NUMBER"
+	KEYWORD = "KEYWORD"
+	PUNCT   = "PUNCT"
+)
+
+// Keywords are the reserved words of Python, including the soft keywords
+var Keywords = strings.Fields(`False None True and as assert async await break
+	case class continue def del elif else except finally for from global if
+	import in is lambda match nonlocal not or pass raise return try while with
+	yield`)
+
+// Tokenize splits Python source into comment, string, number, keyword,
+// identifier and punctuation tokens. Whitespace is left out, and a string
+// that is never closed runs to the end of its line, or the end of the input
+// for a triple-quoted string
+func Tokenize(input string) []lex.Token {
+	tokens := []lex.Token{}
+	line := 1
+
+	for i := 0; i < len(input); {
+		ch := input[i]
+		if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == '\\' {
+			if ch == '\n' {
+				line++
+			}
+			i++
+			continue
+		}
+
+		tok := lex.Token{Line: line, StartPosition: i}
+		switch {
+		case ch == '#':
+			tok.Type = COMMENT
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input) - i
+			}
+			i += end
+		case stringPrefix(input[i:]) >= 0:
+			tok.Type = STRING
+			i = stringEnd(input, i+stringPrefix(input[i:]))
+		case isLetter(ch):
+			for i < len(input) && (isLetter(input[i]) || isDigit(input[i])) {
+				i++
+			}
+			tok.Type = IDENT
+			if slices.Contains(Keywords, input[tok.StartPosition:i]) {
+				tok.Type = KEYWORD
+			}
+		case isDigit(ch) || (ch == '.' && i+1 < len(input) && isDigit(input[i+1])):
+			tok.Type = NUMBER
+			for i < len(input) && (isLetter(input[i]) || isDigit(input[i]) ||
+				input[i] == '.') {
+				i++
+			}
+		default:
+			tok.Type = PUNCT
+			i++
+		}
+
+		tok.EndPosition = i
+		tok.Literal = input[tok.StartPosition:i]
+		line += strings.Count(tok.Literal, "\n")
+		tokens = append(tokens, tok)
+	}
+
+	return tokens
+}
+
+// stringPrefix returns the length of the prefix, such as r or rb, of the
+// string starting at the beginning of s, or -1 if s does not start with a
+// string
+func stringPrefix(s string) int {
+	for i := 0; i < len(s) && i <= 2; i++ {
+		switch s[i] {
+		case '\'', '"':
+			return i
+		case 'r', 'R', 'b', 'B', 'f', 'F', 'u', 'U':
+			continue
+		}
+		return -1
+	}
+	return -1
+}
+
+// stringEnd returns the position just after the string whose opening quote
+// is at pos
+func stringEnd(input string, pos int) int {
+	quote := input[pos : pos+1]
+	if strings.HasPrefix(input[pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+
+	for i := pos + len(quote); i < len(input); i++ {
+		switch {
+		case input[i] == '\\':
+			i++
+		case strings.HasPrefix(input[i:], quote):
+			return i + len(quote)
+		case input[i] == '\n' && len(quote) == 1:
+			return i
+		}
+	}
+	return len(input)
+}